@@ -0,0 +1,188 @@
+package hostdb
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// address families
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+)
+
+// common address roles; any other role may be used
+const (
+	AddressRolePrimary    = "primary"
+	AddressRoleManagement = "management"
+)
+
+// Address is one of the addresses of a host
+type Address struct {
+	IP        string `json:"ip"`
+	Interface string `json:"interface,omitempty"` // optional; e.g. eth0
+	Family    string `json:"family,omitempty"`    // optional; AddressFamilyIPv4 or AddressFamilyIPv6
+	Role      string `json:"role,omitempty"`      // optional; e.g. AddressRolePrimary or AddressRoleManagement
+}
+
+// NewAddress will return an Address, with the Family of the IP
+func NewAddress(ip string, iface string, role string) Address {
+	return Address{IP: ip, Interface: iface, Family: addressFamily(ip), Role: role}
+}
+
+// AllAddresses returns every address of the record; the primary IP comes first,
+// unless it's already one of the Addresses
+func (r Record) AllAddresses() []Address {
+
+	addresses := make([]Address, 0, len(r.Addresses)+1)
+
+	if r.IP != "" {
+		found := false
+		for _, address := range r.Addresses {
+			if sameIP(address.IP, r.IP) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			addresses = append(addresses, NewAddress(r.IP, "", AddressRolePrimary))
+		}
+	}
+
+	return append(addresses, r.Addresses...)
+
+}
+
+// AddressWhereGrouping returns a grouping of WHERE clauses, matching records with the given IP,
+// either as their primary IP, or as any of their addresses. The IP must be valid; it's matched as it's
+// given, and in its canonical form, so e.g. 2001:DB8:0::1 also matches 2001:db8::1.
+func AddressWhereGrouping(ip string) (MariadbWhereGrouping, error) {
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return MariadbWhereGrouping{}, fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	// a valid IP has no LIKE wildcards to escape
+	spellings := []string{ip}
+	if canonical := parsed.String(); canonical != ip {
+		spellings = append(spellings, canonical)
+	}
+
+	operator := "="
+	if len(spellings) > 1 {
+		operator = "IN"
+	}
+
+	grouping := MariadbWhereGrouping{
+		Clauses: []MariadbWhereClause{
+			{
+				Key:      []string{"ip"},
+				Operator: operator,
+				Value:    spellings,
+			},
+		},
+	}
+
+	for _, spelling := range spellings {
+		grouping.Clauses = append(grouping.Clauses, MariadbWhereClause{
+			Relativity: "OR",
+			Key:        []string{"json_extract(addresses, '$[*].ip')"},
+			Operator:   "LIKE",
+			Value:      []string{fmt.Sprintf("%%\"%s\"%%", spelling)},
+		})
+	}
+
+	return grouping, nil
+
+}
+
+// validateAddresses returns the invalid fields of the record's addresses
+func (r Record) validateAddresses(index int) (errs []FieldError) {
+
+	invalid := func(i int, field string, value string, message string) {
+		errs = append(errs, FieldError{Index: index, Field: fmt.Sprintf("addresses[%d].%s", i, field), Value: value, Message: message})
+	}
+
+	primary := 0
+
+	for i, address := range r.Addresses {
+		family := addressFamily(address.IP)
+
+		switch {
+		case address.IP == "":
+			invalid(i, "ip", address.IP, "is required")
+		case family == "":
+			invalid(i, "ip", address.IP, "is not a valid IPv4 or IPv6 address")
+		case address.Family != "" && address.Family != family:
+			invalid(i, "family", address.Family, fmt.Sprintf("doesn't match the IP, which is %s", family))
+		}
+
+		if address.Role == AddressRolePrimary {
+			primary++
+
+			if primary > 1 {
+				invalid(i, "role", address.Role, "there may only be one primary address")
+			}
+
+			if r.IP != "" && !sameIP(address.IP, r.IP) {
+				invalid(i, "ip", address.IP, fmt.Sprintf("the primary address must match the record IP, %s", r.IP))
+			}
+		}
+	}
+
+	return errs
+
+}
+
+// sortedAddresses returns a sorted copy of the addresses, so their order doesn't affect hashes
+func sortedAddresses(addresses []Address) []Address {
+
+	sorted := append([]Address(nil), addresses...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.IP != b.IP {
+			return a.IP < b.IP
+		}
+		if a.Interface != b.Interface {
+			return a.Interface < b.Interface
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.Family < b.Family
+	})
+
+	return sorted
+
+}
+
+// sameIP reports whether a and b are the same IP, however they're written
+func sameIP(a string, b string) bool {
+
+	if parsedA, parsedB := net.ParseIP(a), net.ParseIP(b); parsedA != nil && parsedB != nil {
+		return parsedA.Equal(parsedB)
+	}
+
+	return a == b
+
+}
+
+// addressFamily returns the family of the IP, or an empty string if it's not valid
+func addressFamily(ip string) string {
+
+	parsed := net.ParseIP(ip)
+
+	switch {
+	case parsed == nil:
+		return ""
+	case parsed.To4() != nil:
+		return AddressFamilyIPv4
+	default:
+		return AddressFamilyIPv6
+	}
+
+}
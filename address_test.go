@@ -0,0 +1,172 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAddress(t *testing.T) {
+
+	assert.Equal(t, Address{IP: "10.0.0.1", Interface: "eth0", Family: AddressFamilyIPv4, Role: AddressRolePrimary}, NewAddress("10.0.0.1", "eth0", AddressRolePrimary), "IPv4")
+	assert.Equal(t, AddressFamilyIPv6, NewAddress("2001:db8::1", "", "").Family, "IPv6")
+	assert.Equal(t, "", NewAddress("not an ip", "", "").Family, "invalid")
+
+}
+
+func TestRecord_Addresses_JSON(t *testing.T) {
+
+	record := Record{
+		ID: "a",
+		IP: "10.0.0.1",
+		Addresses: []Address{
+			NewAddress("2001:db8::1", "eth0", ""),
+			NewAddress("192.168.0.1", "ipmi", AddressRoleManagement),
+		},
+	}
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(
+		t,
+		`{"id":"a","ip":"10.0.0.1","addresses":[{"ip":"2001:db8::1","interface":"eth0","family":"ipv6"},{"ip":"192.168.0.1","interface":"ipmi","family":"ipv4","role":"management"}]}`,
+		string(recordBytes),
+		"addresses",
+	)
+
+	// records without addresses are unchanged
+	recordBytes, err = json.Marshal(Record{ID: "a", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"id":"a","ip":"10.0.0.1"}`, string(recordBytes), "no addresses")
+
+}
+
+func TestRecord_AllAddresses(t *testing.T) {
+
+	record := Record{IP: "10.0.0.1", Addresses: []Address{NewAddress("2001:db8::1", "eth0", "")}}
+
+	assert.Equal(t, []Address{
+		NewAddress("10.0.0.1", "", AddressRolePrimary),
+		NewAddress("2001:db8::1", "eth0", ""),
+	}, record.AllAddresses(), "primary IP first")
+
+	record.Addresses = append(record.Addresses, NewAddress("10.0.0.1", "eth1", AddressRolePrimary))
+	assert.Equal(t, record.Addresses, record.AllAddresses(), "primary IP already included")
+
+	assert.Empty(t, Record{}.AllAddresses(), "no addresses")
+
+}
+
+func TestRecord_Validate_Addresses(t *testing.T) {
+
+	record := Record{
+		Type:      "test",
+		Hostname:  "alpha",
+		IP:        "2001:db8::1",
+		Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
+		Addresses: []Address{
+			NewAddress("2001:DB8::1", "eth0", AddressRolePrimary),
+			NewAddress("10.0.0.1", "eth1", ""),
+		},
+	}
+
+	assert.NoError(t, record.Validate(), "valid addresses")
+
+	record.Addresses = []Address{
+		{IP: ""},
+		{IP: "10.0.0.256"},
+		{IP: "10.0.0.1", Family: AddressFamilyIPv6},
+		{IP: "10.0.0.2", Role: AddressRolePrimary},
+	}
+
+	var validationError *ValidationError
+	if !assert.True(t, errors.As(record.Validate(), &validationError), "validation error") {
+		return
+	}
+
+	var fields []string
+	for _, fieldError := range validationError.Errors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	assert.Equal(t, []string{"addresses[0].ip", "addresses[1].ip", "addresses[2].family", "addresses[3].ip"}, fields, "invalid fields")
+
+}
+
+func TestRecord_ComputeHash_Addresses(t *testing.T) {
+
+	a := Record{Type: "test", Addresses: []Address{NewAddress("10.0.0.1", "eth0", ""), NewAddress("10.0.0.2", "eth1", "")}}
+	b := Record{Type: "test", Addresses: []Address{NewAddress("10.0.0.2", "eth1", ""), NewAddress("10.0.0.1", "eth0", "")}}
+	c := Record{Type: "test", Addresses: []Address{NewAddress("10.0.0.1", "eth0", "")}}
+
+	hashA, err := a.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	hashB, err := b.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	hashC, err := c.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, hashA, hashB, "order doesn't matter")
+	assert.NotEqual(t, hashA, hashC, "addresses do")
+
+}
+
+func TestAddressWhereGrouping(t *testing.T) {
+
+	grouping, err := AddressWhereGrouping("10.0.0.1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	where := MariadbWhereClauses{
+		Groups: []MariadbWhereGrouping{
+			{Clauses: []MariadbWhereClause{{Key: []string{"type"}, Value: []string{"test"}}}},
+			grouping,
+		},
+	}
+
+	whereSQL, values, err := where.Stringify()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "WHERE type = ? AND ( ip = ? OR json_extract(addresses, '$[*].ip') LIKE ? ) ", whereSQL, "primary IP or any address")
+	assert.Equal(t, []interface{}{"test", "10.0.0.1", "%\"10.0.0.1\"%"}, values, "values")
+
+	// non-canonical spellings also match the canonical one
+	grouping, err = AddressWhereGrouping("2001:DB8:0::1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	whereSQL, values, err = MariadbWhereClauses{Groups: []MariadbWhereGrouping{grouping}}.Stringify()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "WHERE ( ip IN (?,?) OR json_extract(addresses, '$[*].ip') LIKE ? OR json_extract(addresses, '$[*].ip') LIKE ? ) ", whereSQL, "canonical IP")
+	assert.Equal(t, []interface{}{"2001:DB8:0::1", "2001:db8::1", "%\"2001:DB8:0::1\"%", "%\"2001:db8::1\"%"}, values, "canonical values")
+
+	// wildcards aren't IPs
+	for _, ip := range []string{"%", "10.0.0._", ""} {
+		_, err := AddressWhereGrouping(ip)
+		assert.Error(t, err, ip)
+	}
+
+}
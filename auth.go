@@ -0,0 +1,173 @@
+package hostdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCredentialHelperTTL is how long credentials from a helper command are cached,
+// when the helper doesn't provide an expiry
+const DefaultCredentialHelperTTL = 5 * time.Minute
+
+// ErrMissingCredentials is returned when sending without any credentials, outside of dry-run mode,
+// or by an Authenticator which has no credentials to offer
+var ErrMissingCredentials = errors.New("missing HostDB credentials; set HOSTDB_PASS, or enable dry-run mode")
+
+// Authenticator adds credentials to each request sent to HostDB
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Credentials are the values understood by HostDB; a token takes precedence over a user and pass
+type Credentials struct {
+	User      string    `json:"user,omitempty"`
+	Pass      string    `json:"pass,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Authenticate will add the credentials to the request, as either a bearer token or basic auth
+func (c Credentials) Authenticate(req *http.Request) error {
+
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	case c.Pass != "":
+		user := c.User
+		if user == "" {
+			user = DefaultUser
+		}
+		req.SetBasicAuth(user, c.Pass)
+	default:
+		return ErrMissingCredentials
+	}
+
+	return nil
+
+}
+
+// BasicAuth authenticates using HTTP basic auth
+type BasicAuth struct {
+	User string
+	Pass string
+}
+
+// Authenticate will add basic auth to the request
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	return Credentials{User: a.User, Pass: a.Pass}.Authenticate(req)
+}
+
+// BearerToken authenticates using a static bearer token
+type BearerToken string
+
+// Authenticate will add the bearer token to the request
+func (t BearerToken) Authenticate(req *http.Request) error {
+	return Credentials{Token: string(t)}.Authenticate(req)
+}
+
+// CredentialsFile authenticates using credentials read from a file, such as a mounted Kubernetes secret.
+// The file may contain Credentials as JSON, or just a bearer token.
+// It is read for every request, so rotated credentials are picked up.
+type CredentialsFile struct {
+	Path string
+}
+
+// Authenticate will read the file, and add its credentials to the request
+func (f CredentialsFile) Authenticate(req *http.Request) error {
+
+	fileBytes, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read HostDB credentials file: %w", err)
+	}
+
+	credentials, err := parseCredentials(fileBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse HostDB credentials file %s: %w", f.Path, err)
+	}
+
+	return credentials.Authenticate(req)
+
+}
+
+// CredentialHelper authenticates using the output of an external command,
+// which is cached until it expires. The command may print Credentials as JSON,
+// or just a bearer token.
+type CredentialHelper struct {
+	Command string
+	Args    []string
+
+	// how long to cache credentials which don't include an expiry; defaults to DefaultCredentialHelperTTL
+	TTL time.Duration
+
+	mutex       sync.Mutex
+	credentials Credentials
+	expiresAt   time.Time
+}
+
+// Authenticate will add the cached credentials to the request, running the command if they have expired
+func (h *CredentialHelper) Authenticate(req *http.Request) error {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if time.Now().After(h.expiresAt) {
+
+		command := exec.CommandContext(req.Context(), h.Command, h.Args...)
+
+		var stderr bytes.Buffer
+		command.Stderr = &stderr
+
+		output, err := command.Output()
+		if err != nil {
+			return fmt.Errorf("HostDB credential helper %s failed: %w: %s", h.Command, err, strings.TrimSpace(stderr.String()))
+		}
+
+		credentials, err := parseCredentials(output)
+		if err != nil {
+			return fmt.Errorf("failed to parse output of HostDB credential helper %s: %w", h.Command, err)
+		}
+
+		ttl := h.TTL
+		if ttl <= 0 {
+			ttl = DefaultCredentialHelperTTL
+		}
+
+		h.credentials = credentials
+		h.expiresAt = time.Now().Add(ttl)
+		if !credentials.ExpiresAt.IsZero() {
+			h.expiresAt = credentials.ExpiresAt
+		}
+
+	}
+
+	return h.credentials.Authenticate(req)
+
+}
+
+// parseCredentials will decode credentials in JSON, or treat the whole input as a bearer token
+func parseCredentials(input []byte) (credentials Credentials, err error) {
+
+	input = bytes.TrimSpace(input)
+	if len(input) < 1 {
+		return credentials, ErrMissingCredentials
+	}
+
+	if input[0] != '{' {
+		return Credentials{Token: string(input)}, nil
+	}
+
+	if err := json.Unmarshal(input, &credentials); err != nil {
+		return credentials, err
+	}
+
+	return credentials, nil
+
+}
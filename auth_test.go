@@ -0,0 +1,194 @@
+package hostdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticators(t *testing.T) {
+
+	request := func(auth Authenticator) (*http.Request, error) {
+		req := httptest.NewRequest(http.MethodPost, "/records/", nil)
+		return req, auth.Authenticate(req)
+	}
+
+	// basic auth
+	req, err := request(BasicAuth{User: "user", Pass: "pass"})
+	if assert.NoError(t, err, "basic auth") {
+		user, pass, ok := req.BasicAuth()
+		assert.True(t, ok, "basic auth")
+		assert.Equal(t, "user", user, "user")
+		assert.Equal(t, "pass", pass, "pass")
+	}
+
+	// the default user
+	req, err = request(BasicAuth{Pass: "pass"})
+	if assert.NoError(t, err, "basic auth with the default user") {
+		user, _, _ := req.BasicAuth()
+		assert.Equal(t, DefaultUser, user, "default user")
+	}
+
+	// bearer token
+	req, err = request(BearerToken("abc"))
+	if assert.NoError(t, err, "bearer token") {
+		assert.Equal(t, "Bearer abc", req.Header.Get("Authorization"), "bearer token")
+	}
+
+	_, err = request(BearerToken(""))
+	assert.Equal(t, ErrMissingCredentials, err, "empty token")
+
+	// credentials files
+	dir := t.TempDir()
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("def\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	req, err = request(CredentialsFile{Path: tokenFile})
+	if assert.NoError(t, err, "token file") {
+		assert.Equal(t, "Bearer def", req.Header.Get("Authorization"), "token from file")
+	}
+
+	jsonFile := filepath.Join(dir, "credentials.json")
+	if err := ioutil.WriteFile(jsonFile, []byte(`{"user":"file-user","pass":"file-pass"}`), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	req, err = request(CredentialsFile{Path: jsonFile})
+	if assert.NoError(t, err, "JSON credentials file") {
+		user, pass, _ := req.BasicAuth()
+		assert.Equal(t, "file-user", user, "user from file")
+		assert.Equal(t, "file-pass", pass, "pass from file")
+	}
+
+	_, err = request(CredentialsFile{Path: filepath.Join(dir, "missing")})
+	assert.Error(t, err, "missing credentials file")
+
+}
+
+func TestCredentialHelper_FromEnv(t *testing.T) {
+
+	var tokens []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	// the helper counts how many times it has been run
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "counter")
+	script := filepath.Join(dir, "helper.sh")
+	if err := ioutil.WriteFile(script, []byte(fmt.Sprintf("#!/bin/sh\necho run >> %s\necho '{\"token\":\"helper-token\"}'\n", counter)), 0700); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for k, v := range map[string]string{"HOSTDB_URL": testServer.URL, "HOSTDB_CREDENTIAL_HELPER": script} {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	defer func() {
+		if err := os.Unsetenv("HOSTDB_CREDENTIAL_HELPER"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := TestRecord.Send("test"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	runs, err := ioutil.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 1, strings.Count(string(runs), "run"), "cached between sends")
+	assert.Equal(t, []string{"Bearer helper-token", "Bearer helper-token"}, tokens, "token from helper")
+
+}
+
+func TestCredentialHelper(t *testing.T) {
+
+	// the helper counts how many times it has been run
+	counter := filepath.Join(t.TempDir(), "counter")
+	helper := &CredentialHelper{
+		Command: "sh",
+		Args:    []string{"-c", fmt.Sprintf("echo run >> %s && echo '{\"token\":\"helper-token\"}'", counter)},
+	}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/records/", nil)
+		if err := helper.Authenticate(req); err != nil {
+			t.Fatal(err.Error())
+		}
+		assert.Equal(t, "Bearer helper-token", req.Header.Get("Authorization"), "token from helper")
+	}
+
+	runs, err := ioutil.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 1, strings.Count(string(runs), "run"), "cached until expiry")
+
+	// expired credentials are refreshed
+	helper.expiresAt = time.Now().Add(-time.Second)
+	if err := helper.Authenticate(httptest.NewRequest(http.MethodPost, "/records/", nil)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	runs, err = ioutil.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 2, strings.Count(string(runs), "run"), "refreshed after expiry")
+
+	// a failing helper
+	failing := &CredentialHelper{Command: "sh", Args: []string{"-c", "echo broken >&2; exit 1"}}
+	err = failing.Authenticate(httptest.NewRequest(http.MethodPost, "/records/", nil))
+	if assert.Error(t, err, "failing helper") {
+		assert.Contains(t, err.Error(), "broken", "helper output")
+	}
+
+}
+
+func TestClient_Auth(t *testing.T) {
+
+	var authorization string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Auth: BearerToken("client-token")})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "Bearer client-token", authorization, "authenticator is used")
+
+}
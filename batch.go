@@ -0,0 +1,189 @@
+package hostdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BatchConfig describes how large record sets are split before sending;
+// a zero value means there is no limit
+type BatchConfig struct {
+	MaxRecords int `json:"max_records" mapstructure:"max_records"`
+	MaxBytes   int `json:"max_bytes" mapstructure:"max_bytes"`
+
+	// a posted set may replace the stored records with the same Type and Context, so every batch after
+	// the first is posted with QueryParamAppend; this must be enabled to confirm the server supports it,
+	// otherwise a set which exceeds the limits is refused
+	Append bool `json:"append" mapstructure:"append"`
+}
+
+// ErrBatchAppendDisabled is returned when a RecordSet exceeds the batch limits, but Append isn't enabled
+var ErrBatchAppendDisabled = errors.New("record set exceeds the batch limits, and appending batches isn't enabled")
+
+// BatchResult describes the outcome of sending a single batch
+type BatchResult struct {
+	Batch   int   // the position of this batch, starting at zero
+	Offset  int   // the index of the first record of this batch, within the original set
+	Records int   // the number of records in this batch
+	Err     error // nil, if the batch was sent successfully
+}
+
+// BatchError is returned when one or more batches of a RecordSet could not be sent.
+// Results contains an entry for every batch, including those which were successful.
+type BatchError struct {
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+
+	failed := e.Failed()
+
+	messages := make([]string, 0, len(failed))
+	for _, result := range failed {
+		messages = append(messages, fmt.Sprintf(
+			"batch %d (records %d-%d): %v",
+			result.Batch, result.Offset, result.Offset+result.Records-1, result.Err,
+		))
+	}
+
+	return fmt.Sprintf("%d of %d batches failed: %s", len(failed), len(e.Results), strings.Join(messages, "; "))
+
+}
+
+// Failed returns the results of the batches which could not be sent
+func (e *BatchError) Failed() (failed []BatchResult) {
+
+	for _, result := range e.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+
+}
+
+// FailedRecords returns the indexes, within the original set, of the records in the batches which failed
+func (e *BatchError) FailedRecords() (indexes []int) {
+
+	for _, result := range e.Failed() {
+		for i := 0; i < result.Records; i++ {
+			indexes = append(indexes, result.Offset+i)
+		}
+	}
+
+	return indexes
+
+}
+
+// enabled reports whether either limit has been set
+func (b BatchConfig) enabled() bool {
+	return b.MaxRecords > 0 || b.MaxBytes > 0
+}
+
+// Batches will split the RecordSet into smaller sets, each with the same Type, Timestamp,
+// Context and Committer. Sizes are measured using the JSON encoding of each record;
+// a record which is larger than MaxBytes by itself is placed into its own batch.
+func (rs RecordSet) Batches(config BatchConfig) (batches []RecordSet, err error) {
+
+	if !config.enabled() || len(rs.Records) < 1 {
+		return []RecordSet{rs}, nil
+	}
+
+	// the size of everything except the records themselves
+	envelope := rs
+	envelope.Records = []Record{}
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := envelope
+	batch.Records = nil
+	size := len(envelopeBytes)
+
+	for _, record := range rs.Records {
+
+		recordSize := 0
+		if config.MaxBytes > 0 {
+			recordBytes, err := json.Marshal(record)
+			if err != nil {
+				return nil, err
+			}
+			recordSize = len(recordBytes) + 1 // including the separating comma
+		}
+
+		full := config.MaxRecords > 0 && len(batch.Records) >= config.MaxRecords
+		tooBig := config.MaxBytes > 0 && len(batch.Records) > 0 && size+recordSize > config.MaxBytes
+
+		if full || tooBig {
+			batches = append(batches, batch)
+			batch = envelope
+			batch.Records = nil
+			size = len(envelopeBytes)
+		}
+
+		batch.Records = append(batch.Records, record)
+		size += recordSize
+
+	}
+
+	return append(batches, batch), nil
+
+}
+
+// sendBatches will split the RecordSet according to the client's batch configuration, and post each batch
+// as a set. Only the first batch is posted as usual; the others are posted with QueryParamAppend, so they're
+// added to the first batch rather than replacing it, which the server must support. Without Append, a set
+// which would be split is refused. Every batch is attempted, even if an earlier batch has failed.
+func (c *Client) sendBatches(ctx context.Context, rs RecordSet, uniqueQueryString string) (err error) {
+
+	batches, err := rs.Batches(c.config.Batch)
+	if err != nil {
+		return err
+	}
+
+	if len(batches) == 1 {
+		return c.sendRecordSet(ctx, batches[0], uniqueQueryString, false)
+	}
+
+	if !c.config.Batch.Append {
+		return fmt.Errorf("%d records would be sent in %d batches: %w", len(rs.Records), len(batches), ErrBatchAppendDisabled)
+	}
+
+	var failures int
+	results := make([]BatchResult, 0, len(batches))
+	offset := 0
+
+	for i, batch := range batches {
+
+		result := BatchResult{
+			Batch:   i,
+			Offset:  offset,
+			Records: len(batch.Records),
+		}
+
+		// once canceled, there's no point attempting the remaining batches
+		if result.Err = ctx.Err(); result.Err == nil {
+			result.Err = c.sendRecordSet(ctx, batch, uniqueQueryString, i > 0)
+		}
+
+		if result.Err != nil {
+			failures++
+		}
+
+		results = append(results, result)
+		offset += len(batch.Records)
+
+	}
+
+	if failures > 0 {
+		return &BatchError{Results: results}
+	}
+
+	return nil
+
+}
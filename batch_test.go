@@ -0,0 +1,166 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBatchRecordSet returns a RecordSet with the given number of records
+func newBatchRecordSet(records int) RecordSet {
+
+	rs := TestRecordSet
+	rs.Records = nil
+
+	for i := 0; i < records; i++ {
+		rs.Records = append(rs.Records, Record{
+			ID:       fmt.Sprintf("%04d", i),
+			Hostname: fmt.Sprintf("host-%04d.local", i),
+		})
+	}
+
+	return rs
+
+}
+
+func TestRecordSet_Batches(t *testing.T) {
+
+	rs := newBatchRecordSet(10)
+
+	// no limits
+	batches, err := rs.Batches(BatchConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, batches, 1, "unbatched")
+
+	// by record count
+	batches, err = rs.Batches(BatchConfig{MaxRecords: 4})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if assert.Len(t, batches, 3, "batches by record count") {
+		assert.Len(t, batches[0].Records, 4, "first batch")
+		assert.Len(t, batches[2].Records, 2, "last batch")
+
+		for _, batch := range batches {
+			assert.Equal(t, rs.Type, batch.Type, "type")
+			assert.Equal(t, rs.Timestamp, batch.Timestamp, "timestamp")
+			assert.Equal(t, rs.Context, batch.Context, "context")
+			assert.Equal(t, rs.Committer, batch.Committer, "committer")
+		}
+	}
+
+	// by encoded size
+	maxBytes := 400
+	batches, err = rs.Batches(BatchConfig{MaxBytes: maxBytes})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.True(t, len(batches) > 1, "batches by size")
+
+	var total int
+	for _, batch := range batches {
+		batchBytes, err := json.Marshal(batch)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		assert.True(t, len(batchBytes) <= maxBytes, "batch of %d bytes", len(batchBytes))
+		total += len(batch.Records)
+	}
+
+	assert.Equal(t, len(rs.Records), total, "every record is batched")
+
+	// a record larger than the limit is sent by itself
+	batches, err = rs.Batches(BatchConfig{MaxBytes: 1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, batches, len(rs.Records), "oversized records")
+
+}
+
+func TestClient_SendRecordSet_Batches(t *testing.T) {
+
+	// fake http server which rejects the second batch
+	var posts int
+	var appended []bool
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		posts++
+		appended = append(appended, r.URL.Query().Get(QueryParamAppend) == "true")
+
+		var rs RecordSet
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			t.Error(err.Error())
+		}
+
+		assert.Equal(t, TestRecordSet.Type, rs.Type, "type")
+		assert.Equal(t, TestRecordSet.Committer, rs.Committer, "committer")
+		assert.Equal(t, TestRecordSet.Context, rs.Context, "context")
+		assert.True(t, len(rs.Records) <= 3, "batch size")
+
+		if posts == 2 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Batch: BatchConfig{MaxRecords: 3, Append: true}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = client.SendRecordSet(newBatchRecordSet(7), "test")
+
+	var batchError *BatchError
+	if !assert.True(t, errors.As(err, &batchError), "batch error") {
+		t.FailNow()
+	}
+
+	assert.Equal(t, 3, posts, "every batch is attempted")
+	assert.Equal(t, []bool{false, true, true}, appended, "batches after the first are appended")
+	assert.Len(t, batchError.Results, 3, "results")
+
+	failed := batchError.Failed()
+	if assert.Len(t, failed, 1, "failed batches") {
+		assert.Equal(t, 1, failed[0].Batch, "failed batch")
+		assert.Equal(t, 3, failed[0].Offset, "offset of the failed batch")
+		assert.Equal(t, 3, failed[0].Records, "records in the failed batch")
+
+		var errorResponse ErrorResponse
+		assert.True(t, errors.As(failed[0].Err, &errorResponse), "error response of the failed batch")
+	}
+
+	assert.Equal(t, []int{3, 4, 5}, batchError.FailedRecords(), "failed records")
+	assert.Contains(t, err.Error(), "1 of 3 batches failed", "error message")
+
+	// without append, a set which would be split is refused before anything is sent
+	client, err = NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Batch: BatchConfig{MaxRecords: 3}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.True(t, errors.Is(client.SendRecordSet(newBatchRecordSet(4), "test"), ErrBatchAppendDisabled), "append disabled")
+	assert.Equal(t, 3, posts, "nothing sent")
+
+	// a set within the limits is still sent
+	assert.NoError(t, client.SendRecordSet(newBatchRecordSet(3), "test"), "single batch")
+	assert.Equal(t, []bool{false, true, true, false}, appended, "single batch isn't appended")
+
+}
@@ -0,0 +1,744 @@
+package hostdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultURL is used when no HostDB URL has been configured
+const DefaultURL = "https://hostdb.pdxfixit.com/v0"
+
+// DefaultUser is used when no HostDB user has been configured
+const DefaultUser = "writer"
+
+// DefaultUserAgent is sent with every request, unless overridden
+const DefaultUserAgent = "hostdb-go"
+
+// Client is used to communicate with a single HostDB instance
+type Client struct {
+	config     ClientConfig
+	auth       Authenticator
+	httpClient *http.Client
+	spool      *spool
+}
+
+// ClientConfig contains the configuration parameters for a HostDB client
+type ClientConfig struct {
+	URL       string        `json:"url" mapstructure:"url"`
+	User      string        `json:"user" mapstructure:"user"`
+	Pass      string        `json:"pass" mapstructure:"pass"`
+	UserAgent string        `json:"user_agent" mapstructure:"user_agent"`
+	Timeout   time.Duration `json:"timeout" mapstructure:"timeout"`
+
+	// optional; when provided, User and Pass are ignored
+	Auth Authenticator `json:"-" mapstructure:"-"`
+
+	// TLS; verification is always enabled, unless Insecure is set
+	Insecure bool   `json:"insecure" mapstructure:"insecure"`
+	CAFile   string `json:"ca_file" mapstructure:"ca_file"`
+	CertFile string `json:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `json:"key_file" mapstructure:"key_file"`
+
+	// failed requests are retried according to this policy; by default, they aren't
+	Retry RetryPolicy `json:"retry" mapstructure:"retry"`
+
+	// optional; called before each retry, e.g. for logging
+	OnRetry func(RetryAttempt) `json:"-" mapstructure:"-"`
+
+	// request bodies are compressed with gzip, when enabled
+	Gzip bool `json:"gzip" mapstructure:"gzip"`
+
+	// in dry-run mode, nothing is sent; requests are logged, and payloads are written to
+	// DryRunWriter and/or saved as <type>.json into DryRunDir, when set
+	DryRun       bool      `json:"dry_run" mapstructure:"dry_run"`
+	DryRunDir    string    `json:"dry_run_dir" mapstructure:"dry_run_dir"`
+	DryRunWriter io.Writer `json:"-" mapstructure:"-"`
+
+	// payloads which can't be delivered are kept in the spool directory, when set
+	Spool SpoolConfig `json:"spool" mapstructure:"spool"`
+
+	// record sets are split into batches, when either limit is set
+	Batch BatchConfig `json:"batch" mapstructure:"batch"`
+
+	// optional; defaults to the Logger set by SetLogger, which discards everything
+	Logger Logger `json:"-" mapstructure:"-"`
+
+	// optional; defaults to DefaultMetrics, which are published with expvar
+	Metrics Metrics `json:"-" mapstructure:"-"`
+
+	// optional; when provided, Timeout and the TLS options are ignored
+	HTTPClient *http.Client `json:"-" mapstructure:"-"`
+}
+
+var (
+	defaultClient      *Client
+	envClient          *Client // configured from the environment, and reused until it changes
+	envClientKey       string  // the HOSTDB_* variables which envClient was configured from
+	defaultClientMutex sync.Mutex
+)
+
+// NewClient will return a client for the HostDB instance described by config
+func NewClient(config ClientConfig) (*Client, error) {
+
+	if config.URL == "" {
+		config.URL = DefaultURL
+	} else if _, err := url.ParseRequestURI(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid HostDB URL %s: %v", config.URL, err)
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+
+	if config.User == "" {
+		config.User = DefaultUser
+	}
+
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		transport, err := newTransport(config)
+		if err != nil {
+			return nil, err
+		}
+
+		httpClient = &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		}
+	}
+
+	auth := config.Auth
+	if auth == nil && config.Pass != "" {
+		auth = BasicAuth{User: config.User, Pass: config.Pass}
+	}
+
+	client := &Client{
+		config:     config,
+		auth:       auth,
+		httpClient: httpClient,
+	}
+
+	if config.Spool.Dir != "" {
+		client.spool = &spool{config: config.Spool, logger: client.logger}
+	}
+
+	return client, nil
+
+}
+
+// NewClientFromEnv will return a client configured by the environment variables
+// HOSTDB_URL, HOSTDB_USER, HOSTDB_PASS, HOSTDB_TOKEN, HOSTDB_CREDENTIALS_FILE,
+// HOSTDB_CREDENTIAL_HELPER, HOSTDB_TIMEOUT, HOSTDB_INSECURE,
+// HOSTDB_CA_FILE, HOSTDB_CERT_FILE, HOSTDB_KEY_FILE, HOSTDB_MAX_ATTEMPTS,
+// HOSTDB_BATCH_RECORDS, HOSTDB_BATCH_BYTES, HOSTDB_BATCH_APPEND, HOSTDB_GZIP, HOSTDB_DRY_RUN,
+// HOSTDB_DRY_RUN_DIR, HOSTDB_SPOOL_DIR, HOSTDB_SPOOL_MAX_AGE and HOSTDB_SPOOL_MAX_BYTES.
+// When HOSTDB_MAX_ATTEMPTS is set, the DefaultRetryPolicy delays are used.
+func NewClientFromEnv() (*Client, error) {
+
+	config := ClientConfig{
+		URL:      os.Getenv("HOSTDB_URL"),
+		User:     os.Getenv("HOSTDB_USER"),
+		Pass:     os.Getenv("HOSTDB_PASS"),
+		CAFile:   os.Getenv("HOSTDB_CA_FILE"),
+		CertFile: os.Getenv("HOSTDB_CERT_FILE"),
+		KeyFile:  os.Getenv("HOSTDB_KEY_FILE"),
+
+		DryRunDir: os.Getenv("HOSTDB_DRY_RUN_DIR"),
+
+		Spool: SpoolConfig{Dir: os.Getenv("HOSTDB_SPOOL_DIR")},
+	}
+
+	// in order of precedence, ahead of HOSTDB_USER and HOSTDB_PASS
+	if helper := strings.Fields(os.Getenv("HOSTDB_CREDENTIAL_HELPER")); len(helper) > 0 {
+		config.Auth = &CredentialHelper{Command: helper[0], Args: helper[1:]}
+	} else if credentialsFile := os.Getenv("HOSTDB_CREDENTIALS_FILE"); credentialsFile != "" {
+		config.Auth = CredentialsFile{Path: credentialsFile}
+	} else if token := os.Getenv("HOSTDB_TOKEN"); token != "" {
+		config.Auth = BearerToken(token)
+	}
+
+	if timeout := os.Getenv("HOSTDB_TIMEOUT"); timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_TIMEOUT is invalid: %v", err)
+		}
+		config.Timeout = duration
+	}
+
+	if insecure := os.Getenv("HOSTDB_INSECURE"); insecure != "" {
+		enabled, err := strconv.ParseBool(insecure)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_INSECURE is invalid: %v", err)
+		}
+		config.Insecure = enabled
+	}
+
+	if maxAttempts := os.Getenv("HOSTDB_MAX_ATTEMPTS"); maxAttempts != "" {
+		attempts, err := strconv.Atoi(maxAttempts)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_MAX_ATTEMPTS is invalid: %v", err)
+		}
+		config.Retry = DefaultRetryPolicy
+		config.Retry.MaxAttempts = attempts
+	}
+
+	if batchRecords := os.Getenv("HOSTDB_BATCH_RECORDS"); batchRecords != "" {
+		records, err := strconv.Atoi(batchRecords)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_BATCH_RECORDS is invalid: %v", err)
+		}
+		config.Batch.MaxRecords = records
+	}
+
+	if batchBytes := os.Getenv("HOSTDB_BATCH_BYTES"); batchBytes != "" {
+		size, err := strconv.Atoi(batchBytes)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_BATCH_BYTES is invalid: %v", err)
+		}
+		config.Batch.MaxBytes = size
+	}
+
+	if batchAppend := os.Getenv("HOSTDB_BATCH_APPEND"); batchAppend != "" {
+		enabled, err := strconv.ParseBool(batchAppend)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_BATCH_APPEND is invalid: %v", err)
+		}
+		config.Batch.Append = enabled
+	}
+
+	if gzip := os.Getenv("HOSTDB_GZIP"); gzip != "" {
+		enabled, err := strconv.ParseBool(gzip)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_GZIP is invalid: %v", err)
+		}
+		config.Gzip = enabled
+	}
+
+	if dryRun := os.Getenv("HOSTDB_DRY_RUN"); dryRun != "" {
+		enabled, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_DRY_RUN is invalid: %v", err)
+		}
+		config.DryRun = enabled
+
+		// the default Logger discards everything, so payloads must be reported somewhere,
+		// otherwise a dry run would look like a silent success
+		config.DryRunWriter = os.Stderr
+	}
+
+	if maxAge := os.Getenv("HOSTDB_SPOOL_MAX_AGE"); maxAge != "" {
+		duration, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_SPOOL_MAX_AGE is invalid: %v", err)
+		}
+		config.Spool.MaxAge = duration
+	}
+
+	if maxBytes := os.Getenv("HOSTDB_SPOOL_MAX_BYTES"); maxBytes != "" {
+		size, err := strconv.ParseInt(maxBytes, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("HOSTDB_SPOOL_MAX_BYTES is invalid: %v", err)
+		}
+		config.Spool.MaxBytes = size
+	}
+
+	return NewClient(config)
+
+}
+
+// SetDefaultClient will set the client used by Record.Send and RecordSet.Send.
+// If no default client is set (or it is set to nil), a client is configured from the environment,
+// and reused for as long as the HOSTDB_* variables are unchanged.
+func SetDefaultClient(client *Client) {
+
+	defaultClientMutex.Lock()
+	defer defaultClientMutex.Unlock()
+
+	defaultClient = client
+
+	if envClient != nil {
+		envClient.httpClient.CloseIdleConnections()
+		envClient = nil
+	}
+
+}
+
+// getDefaultClient returns the default client, or the client configured from the environment
+func getDefaultClient() (*Client, error) {
+
+	defaultClientMutex.Lock()
+	defer defaultClientMutex.Unlock()
+
+	if defaultClient != nil {
+		return defaultClient, nil
+	}
+
+	// the environment is read on every call, as it always has been, but the client
+	// (and its connections and credentials) is only replaced when the environment changes
+	key := hostdbEnv()
+	if envClient != nil && key != envClientKey {
+		envClient.httpClient.CloseIdleConnections()
+		envClient = nil
+	}
+
+	if envClient == nil {
+		client, err := NewClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		envClient = client
+		envClientKey = key
+	}
+
+	return envClient, nil
+
+}
+
+// hostdbEnv returns every HOSTDB_* environment variable, in a stable order
+func hostdbEnv() string {
+
+	var variables []string
+	for _, variable := range os.Environ() {
+		if strings.HasPrefix(variable, "HOSTDB_") {
+			variables = append(variables, variable)
+		}
+	}
+
+	sort.Strings(variables)
+
+	return strings.Join(variables, "\n")
+
+}
+
+// URL returns the base URL for the HostDB instance
+func (c *Client) URL() string {
+	return c.config.URL
+}
+
+// SendRecord will PUT a single record into HostDB; the record must have an ID
+func (c *Client) SendRecord(r Record, uniqueIdentifier string) (err error) {
+	return c.SendRecordContext(context.Background(), r, uniqueIdentifier)
+}
+
+// SendRecordContext will PUT a single record into HostDB, until ctx is canceled
+func (c *Client) SendRecordContext(ctx context.Context, r Record, uniqueIdentifier string) (err error) {
+
+	// otherwise, we'd PUT /records/
+	if r.ID == "" {
+		return ErrMissingID
+	}
+
+	// ensure we have a unique identifier, which is used when viewing logs
+	if uniqueIdentifier == "" {
+		uniqueIdentifier = r.Type
+	}
+
+	// post data to HostDB
+	responseBytes, err := c.request(
+		ctx,
+		"PUT",
+		fmt.Sprintf("/records/%s", url.PathEscape(r.ID)),
+		uniqueIdentifier,
+		r,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	// unmarshal the response into a struct
+	var response PutRecordResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return err
+	}
+
+	// if something's not OK, return an error
+	if response.OK != true {
+		return fmt.Errorf("failed to save record %s", response.ID)
+	}
+
+	c.metrics().AddRecords(r.Type, 1)
+	c.logger().Info("sent record to HostDB", Fields{
+		"type":              r.Type,
+		"id":                r.ID,
+		"records":           1,
+		"unique_identifier": uniqueIdentifier,
+	})
+
+	return nil
+
+}
+
+// SendRecordSet will post the RecordSet to HostDB, if credentials are present.
+// The input variable uniqueQueryString is used when viewing traffic logs --
+// it has no impact on function
+func (c *Client) SendRecordSet(rs RecordSet, uniqueQueryString string) (err error) {
+	return c.SendRecordSetContext(context.Background(), rs, uniqueQueryString)
+}
+
+// SendRecordSetContext will post the RecordSet to HostDB, until ctx is canceled
+func (c *Client) SendRecordSetContext(ctx context.Context, rs RecordSet, uniqueQueryString string) (err error) {
+
+	// ensure we have a unique identifier, which is used when viewing logs
+	if uniqueQueryString == "" {
+		uniqueQueryString = fmt.Sprintf("?type=%s", rs.Type)
+	}
+
+	if rs.HashRecords {
+		if rs, err = rs.WithHashes(); err != nil {
+			return err
+		}
+	}
+
+	// in dry-run mode, keep a copy of the whole set for inspection
+	if c.config.DryRun && c.config.DryRunDir != "" {
+		if err := rs.Save(filepath.Join(c.config.DryRunDir, fmt.Sprintf("%s.json", rs.Type))); err != nil {
+			return err
+		}
+	}
+
+	// large record sets may be split into batches
+	if c.config.Batch.enabled() {
+		return c.sendBatches(ctx, rs, uniqueQueryString)
+	}
+
+	return c.sendRecordSet(ctx, rs, uniqueQueryString, false)
+
+}
+
+// sendRecordSet will post the RecordSet to HostDB in a single request;
+// when appending, its records are added to those already stored, rather than replacing them
+func (c *Client) sendRecordSet(ctx context.Context, rs RecordSet, uniqueQueryString string, appendRecords bool) (err error) {
+
+	// let the user know we're starting
+	c.logger().Info("sending record set to HostDB", Fields{
+		"type":              rs.Type,
+		"records":           len(rs.Records),
+		"unique_identifier": uniqueQueryString,
+	})
+	start := time.Now()
+
+	path := fmt.Sprintf("/records/?%s", uniqueQueryString)
+	if appendRecords {
+		path += appendQueryString
+	}
+
+	// post data to HostDB
+	responseBytes, err := c.request(
+		ctx,
+		"POST",
+		path,
+		uniqueQueryString,
+		rs,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	// unmarshal the response into a struct
+	var response PostRecordsResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return err
+	}
+
+	// if something's not OK, return an error
+	if response.OK != true {
+		return errors.New(response.Error)
+	}
+
+	// let the user know we're done
+	c.metrics().AddRecords(rs.Type, len(rs.Records))
+	c.logger().Info("sent record set to HostDB", Fields{
+		"type":              rs.Type,
+		"records":           len(rs.Records),
+		"unique_identifier": uniqueQueryString,
+		"duration":          time.Since(start),
+	})
+
+	return nil
+
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, uniqueIdentifier string, requestBody interface{}, header map[string]string) (responseBytes []byte, err error) {
+
+	// reads are always sent, and don't require credentials
+	if method != http.MethodGet {
+		if c.config.DryRun {
+			return c.dryRun(method, path, uniqueIdentifier, requestBody)
+		}
+
+		if c.auth == nil {
+			return nil, ErrMissingCredentials
+		}
+	}
+
+	start := time.Now()
+	responseBytes, attempts, retry, err := c.send(ctx, method, path, uniqueIdentifier, requestBody, header)
+
+	// older payloads for the same request are now stale, unless this was only appended to them
+	if err == nil && c.spool != nil && requestBody != nil && !strings.HasSuffix(path, appendQueryString) {
+		if err := c.spool.supersede(method, path, start); err != nil {
+			c.logger().Warn("failed to discard superseded spooled entries", Fields{"method": method, "path": path, "error": err})
+		}
+	}
+
+	// payloads which couldn't be delivered are kept, to be resent later
+	if err != nil && retry && c.spool != nil && requestBody != nil {
+		file, spoolErr := c.spool.add(method, path, uniqueIdentifier, attempts, requestBody)
+		if spoolErr != nil {
+			c.logger().Error("failed to spool undelivered payload", Fields{
+				"method":            method,
+				"path":              path,
+				"unique_identifier": uniqueIdentifier,
+				"error":             spoolErr,
+			})
+			return responseBytes, err
+		}
+
+		return responseBytes, &SpooledError{File: file, Err: err}
+	}
+
+	return responseBytes, err
+
+}
+
+// send will make attempts at a request, according to the retry policy, reporting how many
+// attempts were made, and whether the final failure could have been retried
+func (c *Client) send(ctx context.Context, method string, path string, uniqueIdentifier string, requestBody interface{}, header map[string]string) (responseBytes []byte, attempts int, retry bool, err error) {
+
+	if len(header) < 1 {
+		header = make(map[string]string)
+	}
+
+	// the body is streamed as JSON
+	if requestBody != nil {
+		if _, found := header["Content-Type"]; !found {
+			header["Content-Type"] = "application/json"
+		}
+
+		if c.config.Gzip {
+			header["Content-Encoding"] = "gzip"
+		}
+	}
+
+	maxAttempts := c.config.Retry.attempts()
+	for attempt := 1; ; attempt++ {
+
+		start := time.Now()
+		result := c.do(ctx, method, path, requestBody, header)
+		duration := time.Since(start)
+
+		c.metrics().ObserveRequest(method, result.statusCode, duration, result.bytesSent)
+
+		fields := Fields{
+			"method":            method,
+			"path":              path,
+			"unique_identifier": uniqueIdentifier,
+			"attempt":           attempt,
+			"status":            result.statusCode,
+			"duration":          duration,
+		}
+		if result.err != nil {
+			fields["error"] = result.err
+		}
+		c.logger().Debug("HostDB request", fields)
+
+		responseBytes, retry, err = result.responseBytes, result.retry, result.err
+		if err == nil || !retry || attempt >= maxAttempts {
+			return responseBytes, attempt, retry, err
+		}
+
+		delay := c.config.Retry.delay(attempt, result.retryAfter)
+		c.logger().Warn("retrying HostDB request", Fields{
+			"method":            method,
+			"path":              path,
+			"unique_identifier": uniqueIdentifier,
+			"attempt":           attempt,
+			"status":            result.statusCode,
+			"delay":             delay,
+			"error":             err,
+		})
+
+		if c.config.OnRetry != nil {
+			c.config.OnRetry(RetryAttempt{
+				UniqueIdentifier: uniqueIdentifier,
+				Method:           method,
+				Path:             path,
+				Attempt:          attempt,
+				Delay:            delay,
+				Err:              err,
+			})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return responseBytes, attempt, false, ctx.Err()
+		case <-timer.C:
+		}
+
+	}
+
+}
+
+// attemptResult describes the outcome of a single attempt at a request
+type attemptResult struct {
+	responseBytes []byte
+	bytesSent     int64
+	statusCode    int           // zero, if no response was received
+	retry         bool          // whether a failure may be retried
+	retryAfter    time.Duration // as requested by the server
+	err           error
+}
+
+// do will make a single attempt at a request
+func (c *Client) do(ctx context.Context, method string, path string, requestBody interface{}, header map[string]string) (result attemptResult) {
+
+	if err := waitForRateLimiter(ctx); err != nil {
+		return attemptResult{err: err}
+	}
+
+	// each attempt encodes a fresh body, so nothing is held in memory between attempts
+	var body *streamingBody
+	var reqBody io.Reader
+	if requestBody != nil {
+		body = newStreamingBody(requestBody, c.config.Gzip)
+		reqBody = body
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.URL+path, reqBody)
+	if err != nil {
+		if body != nil {
+			_ = body.Close()
+		}
+		return attemptResult{err: fmt.Errorf("failed to create %s request for %s: %w", method, path, err)}
+	}
+
+	// headers
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	for k, v := range header {
+		req.Header.Add(k, v)
+	}
+
+	// credentials are added to every attempt, so they can be refreshed between attempts
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req); err != nil {
+			if body != nil {
+				_ = body.Close()
+			}
+			return attemptResult{err: err}
+		}
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		// a body which can't be encoded will never succeed
+		if body != nil {
+			if encodingErr := body.encodingError(); encodingErr != nil {
+				return attemptResult{err: encodingErr}
+			}
+		}
+
+		// there's no point retrying once the context is done
+		return attemptResult{
+			retry: ctx.Err() == nil,
+			err:   fmt.Errorf("failed to %s %s: %w", method, path, err),
+		}
+	}
+
+	result.statusCode = res.StatusCode
+	if body != nil {
+		result.bytesSent = body.bytesRead()
+	}
+
+	result.responseBytes, err = ioutil.ReadAll(res.Body)
+	if closeErr := res.Body.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		result.responseBytes = nil
+		result.retry = true
+		result.err = fmt.Errorf("failed to read response to %s %s: %w", method, path, err)
+		return result
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		result.retry = retryableStatus(res.StatusCode)
+		result.retryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		result.err = newErrorResponse(res.StatusCode, result.responseBytes)
+	}
+
+	return result
+
+}
+
+// dryRun will log the request which would have been sent, and capture its payload,
+// responding as HostDB would to a successful request
+func (c *Client) dryRun(method string, path string, uniqueIdentifier string, requestBody interface{}) (responseBytes []byte, err error) {
+
+	c.logger().Info("dry run; request not sent", Fields{
+		"method":            method,
+		"url":               c.config.URL + path,
+		"unique_identifier": uniqueIdentifier,
+	})
+
+	if c.config.DryRunWriter != nil && requestBody != nil {
+		if err := encodeJSON(c.config.DryRunWriter, requestBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte(`{"ok":true}`), nil
+
+}
+
+// newErrorResponse will use the error message provided by HostDB, if there is one,
+// otherwise falling back to the response body, or the status text
+func newErrorResponse(statusCode int, responseBytes []byte) ErrorResponse {
+
+	var genericError GenericError
+	if err := json.Unmarshal(responseBytes, &genericError); err == nil && genericError.Error != "" {
+		return ErrorResponse{Code: statusCode, Message: genericError.Error}
+	}
+
+	message := strings.TrimSpace(string(responseBytes))
+	if message == "" || !utf8.ValidString(message) {
+		message = http.StatusText(statusCode)
+	}
+
+	return ErrorResponse{Code: statusCode, Message: message}
+
+}
+
+// get will GET the path, and unmarshal the response into v
+func (c *Client) get(ctx context.Context, path string, v interface{}) (err error) {
+
+	responseBytes, err := c.request(ctx, http.MethodGet, path, path, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(responseBytes, v); err != nil {
+		return fmt.Errorf("failed to decode response to GET %s: %w", path, err)
+	}
+
+	return nil
+
+}
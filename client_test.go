@@ -0,0 +1,356 @@
+package hostdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+
+	// defaults
+	client, err := NewClient(ClientConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, DefaultURL, client.URL(), "default URL")
+	assert.Equal(t, DefaultUser, client.config.User, "default user")
+	assert.Equal(t, DefaultUserAgent, client.config.UserAgent, "default user agent")
+
+	// trailing slashes are removed
+	client, err = NewClient(ClientConfig{URL: "https://hostdb.example.com/v0/", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "https://hostdb.example.com/v0", client.URL(), "trimmed URL")
+	assert.Equal(t, time.Second, client.httpClient.Timeout, "timeout")
+
+	// invalid URL
+	_, err = NewClient(ClientConfig{URL: "not a url"})
+	assert.Error(t, err, "invalid URL")
+
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+
+	if err := os.Setenv("HOSTDB_URL", "https://hostdb.example.com/v0"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := os.Setenv("HOSTDB_TIMEOUT", "5s"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer func() {
+		if err := os.Unsetenv("HOSTDB_TIMEOUT"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "https://hostdb.example.com/v0", client.URL(), "URL from environment")
+	assert.Equal(t, 5*time.Second, client.httpClient.Timeout, "timeout from environment")
+
+	// invalid timeout
+	if err := os.Setenv("HOSTDB_TIMEOUT", "soon"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = NewClientFromEnv()
+	assert.Error(t, err, "invalid timeout")
+
+}
+
+func TestClient_SendRecordSet_MultipleInstances(t *testing.T) {
+
+	// two fake http servers, each counting the record sets it receives
+	var first, second int
+
+	firstServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first++
+		assert.Equal(t, DefaultUserAgent, r.UserAgent(), "user agent")
+		_, err := fmt.Fprintln(w, "{\"ok\":true}")
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer firstServer.Close()
+
+	secondServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		second++
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok, "basic auth")
+		assert.Equal(t, "other", user, "user")
+		assert.Equal(t, "secret", pass, "pass")
+		_, err := fmt.Fprintln(w, "{\"ok\":true}")
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer secondServer.Close()
+
+	firstClient, err := NewClient(ClientConfig{URL: firstServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	secondClient, err := NewClient(ClientConfig{URL: secondServer.URL, User: "other", Pass: "secret"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := firstClient.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := secondClient.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := secondClient.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 1, first, "requests to the first server")
+	assert.Equal(t, 2, second, "requests to the second server")
+
+}
+
+func TestSetDefaultClient(t *testing.T) {
+
+	var requests int
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, err := fmt.Fprintln(w, "{\"ok\":true}")
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	SetDefaultClient(client)
+	defer SetDefaultClient(nil)
+
+	if err := TestRecordSet.Send("test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 1, requests, "requests sent via the default client")
+
+}
+
+func TestGetDefaultClient_FromEnv(t *testing.T) {
+
+	if err := os.Setenv("HOSTDB_URL", "https://hostdb.example.com/v0"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	SetDefaultClient(nil)
+	defer SetDefaultClient(nil)
+
+	first, err := getDefaultClient()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	second, err := getDefaultClient()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.True(t, first == second, "the client from the environment is reused")
+
+	// changing the environment replaces it
+	if err := os.Setenv("HOSTDB_URL", "https://hostdb2.example.com/v0"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	third, err := getDefaultClient()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.False(t, first == third, "the environment is read again")
+	assert.Equal(t, "https://hostdb2.example.com/v0", third.URL(), "URL from the changed environment")
+
+}
+
+func TestClient_request_Errors(t *testing.T) {
+
+	responses := map[int]string{
+		http.StatusUnauthorized:        "{\"error\":\"invalid credentials\"}",
+		http.StatusNotFound:            "",
+		http.StatusConflict:            "{\"error\":\"conflicting record\"}",
+		http.StatusInternalServerError: "database unavailable",
+	}
+
+	for code, body := range responses {
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			_, err := fmt.Fprint(w, body)
+			if err != nil {
+				t.Error(err.Error())
+			}
+		}))
+
+		client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		err = client.SendRecordSet(TestRecordSet, "test")
+		testServer.Close()
+
+		var errorResponse ErrorResponse
+		if !assert.True(t, errors.As(err, &errorResponse), "error response for %d", code) {
+			continue
+		}
+
+		assert.Equal(t, code, errorResponse.Code, "status code")
+
+		switch code {
+		case http.StatusUnauthorized:
+			assert.Equal(t, "invalid credentials", errorResponse.Message, "decoded error message")
+		case http.StatusNotFound:
+			assert.Equal(t, "Not Found", errorResponse.Message, "status text")
+		case http.StatusConflict:
+			assert.Equal(t, "conflicting record", errorResponse.Message, "decoded error message")
+		case http.StatusInternalServerError:
+			assert.Equal(t, "database unavailable", errorResponse.Message, "response body")
+		}
+
+	}
+
+	// network errors are returned, rather than being fatal
+	testServer := httptest.NewServer(http.NotFoundHandler())
+	testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = client.SendRecord(TestRecord, "test")
+	assert.Error(t, err, "network error")
+	assert.False(t, errors.As(err, &ErrorResponse{}), "network errors are not error responses")
+
+}
+
+func TestClient_SendRecordSetContext(t *testing.T) {
+
+	// fake http server which never responds in time
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	client, err := NewClient(ClientConfig{
+		URL:   testServer.URL,
+		Pass:  "pass",
+		Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = client.SendRecordSetContext(ctx, TestRecordSet, "test")
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "deadline exceeded")
+	assert.True(t, time.Since(start) < time.Minute, "in-flight request and retries are abandoned")
+
+}
+
+func TestClient_SendRecordContext_CanceledDuringRetry(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer testServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := NewClient(ClientConfig{
+		URL:   testServer.URL,
+		Pass:  "pass",
+		Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour},
+		OnRetry: func(attempt RetryAttempt) {
+			cancel()
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = client.SendRecordContext(ctx, TestRecord, "test")
+	assert.True(t, errors.Is(err, context.Canceled), "canceled while waiting to retry")
+
+}
+
+func TestClient_DryRun(t *testing.T) {
+
+	var requests int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer testServer.Close()
+
+	var captured bytes.Buffer
+	client, err := NewClient(ClientConfig{URL: testServer.URL, DryRun: true, DryRunWriter: &captured})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected, err := json.Marshal(TestRecordSet)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, string(expected), captured.String(), "captured payload")
+	assert.Equal(t, 0, requests, "nothing is sent")
+
+	// without dry-run mode, missing credentials are an error
+	client, err = NewClient(ClientConfig{URL: testServer.URL})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, ErrMissingCredentials, client.SendRecord(TestRecord, "test"), "missing credentials")
+	assert.Equal(t, 0, requests, "nothing is sent")
+
+}
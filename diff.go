@@ -0,0 +1,300 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kinds of change, named as RFC 6902 operations
+const (
+	ChangeAdd     = "add"
+	ChangeRemove  = "remove"
+	ChangeReplace = "replace"
+)
+
+// Change is a difference between two records, at a JSON pointer such as /context/region or /data/disks/0/size
+type Change struct {
+	Op   string      `json:"op"`
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"` // the old value; unset when added
+	To   interface{} `json:"to,omitempty"`   // the new value; unset when removed
+}
+
+// RecordDiff lists the changes from one record to another
+type RecordDiff struct {
+	ID      string   `json:"id,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+// PatchOperation is an RFC 6902 JSON Patch operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// RecordSetDiff describes the records added, removed and modified between two record sets
+type RecordSetDiff struct {
+	Added     []Record     `json:"added"`
+	Removed   []Record     `json:"removed"`
+	Modified  []RecordDiff `json:"modified"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// Diff will compare every field of two records, including the contents of Context and Data,
+// returning the changes from a to b
+func Diff(a Record, b Record) (RecordDiff, error) {
+
+	d := RecordDiff{ID: b.ID}
+	if d.ID == "" {
+		d.ID = a.ID
+	}
+
+	aValue, err := jsonValue(a)
+	if err != nil {
+		return d, err
+	}
+
+	bValue, err := jsonValue(b)
+	if err != nil {
+		return d, err
+	}
+
+	diffValues("", aValue, bValue, &d.Changes)
+
+	return d, nil
+
+}
+
+// Patch returns the changes as an RFC 6902 JSON Patch, which transforms the JSON of the first record into the second
+func (d RecordDiff) Patch() []PatchOperation {
+
+	patch := make([]PatchOperation, 0, len(d.Changes))
+	for _, change := range d.Changes {
+		patch = append(patch, PatchOperation{Op: change.Op, Path: change.Path, Value: change.To})
+	}
+
+	return patch
+
+}
+
+// MarshalJSON omits the value of remove operations
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+
+	if o.Op == ChangeRemove {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+
+	type operation PatchOperation
+
+	return json.Marshal(operation(o))
+
+}
+
+// DiffRecordSets will compare two record sets. Records are matched by ID, and otherwise by hash;
+// matched records are modified if their hashes differ, so changes to volatile fields such as Timestamp are ignored.
+// Records without a Hash are hashed with ComputeHash, using the Type and Context they inherit from their set.
+func DiffRecordSets(a RecordSet, b RecordSet) (d RecordSetDiff, err error) {
+
+	aHashes, err := a.contentHashes()
+	if err != nil {
+		return d, err
+	}
+
+	bHashes, err := b.contentHashes()
+	if err != nil {
+		return d, err
+	}
+
+	matched := make([]int, len(b.Records)) // the index in a of each record in b, plus one
+	aMatched := make([]bool, len(a.Records))
+
+	// by ID
+	bByID := map[string]int{}
+	for i, r := range b.Records {
+		if r.ID != "" {
+			bByID[r.ID] = i
+		}
+	}
+
+	for i, r := range a.Records {
+		if j, found := bByID[r.ID]; found && r.ID != "" && matched[j] == 0 {
+			matched[j] = i + 1
+			aMatched[i] = true
+		}
+	}
+
+	// then by hash
+	bByHash := map[string][]int{}
+	for j := range b.Records {
+		if matched[j] == 0 {
+			bByHash[bHashes[j]] = append(bByHash[bHashes[j]], j)
+		}
+	}
+
+	for i := range a.Records {
+		if aMatched[i] {
+			continue
+		}
+
+		if candidates := bByHash[aHashes[i]]; len(candidates) > 0 {
+			matched[candidates[0]] = i + 1
+			aMatched[i] = true
+			bByHash[aHashes[i]] = candidates[1:]
+		}
+	}
+
+	for i, r := range a.Records {
+		if !aMatched[i] {
+			d.Removed = append(d.Removed, r)
+		}
+	}
+
+	for j, r := range b.Records {
+		if matched[j] == 0 {
+			d.Added = append(d.Added, r)
+			continue
+		}
+
+		i := matched[j] - 1
+		if aHashes[i] == bHashes[j] {
+			d.Unchanged++
+			continue
+		}
+
+		recordDiff, err := Diff(a.Records[i], r)
+		if err != nil {
+			return d, err
+		}
+
+		d.Modified = append(d.Modified, recordDiff)
+	}
+
+	return d, nil
+
+}
+
+// contentHashes returns the Hash of every record, computing those which are unset as the server will store them
+func (rs RecordSet) contentHashes() ([]string, error) {
+
+	hashes := make([]string, len(rs.Records))
+
+	for i, r := range rs.Records {
+		if r.Hash != "" {
+			hashes[i] = r.Hash
+			continue
+		}
+
+		hash, err := rs.inherit(r).ComputeHash()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hash
+	}
+
+	return hashes, nil
+
+}
+
+// jsonValue returns v as decoded JSON, with normalized numbers
+func jsonValue(v interface{}) (interface{}, error) {
+
+	canonicalBytes, err := canonicalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := unmarshalNumbers(canonicalBytes, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+
+}
+
+// diffValues appends the changes from a to b, at the JSON pointer path
+func diffValues(path string, a interface{}, b interface{}, changes *[]Change) {
+
+	switch aValue := a.(type) {
+	case map[string]interface{}:
+		if bValue, ok := b.(map[string]interface{}); ok {
+			diffObjects(path, aValue, bValue, changes)
+			return
+		}
+	case []interface{}:
+		if bValue, ok := b.([]interface{}); ok {
+			diffArrays(path, aValue, bValue, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Op: ChangeReplace, Path: path, From: a, To: b})
+	}
+
+}
+
+func diffObjects(path string, a map[string]interface{}, b map[string]interface{}, changes *[]Change) {
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, found := a[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		keyPath := path + "/" + escapePointer(k)
+		aValue, inA := a[k]
+		bValue, inB := b[k]
+
+		switch {
+		case !inB:
+			*changes = append(*changes, Change{Op: ChangeRemove, Path: keyPath, From: aValue})
+		case !inA:
+			*changes = append(*changes, Change{Op: ChangeAdd, Path: keyPath, To: bValue})
+		default:
+			diffValues(keyPath, aValue, bValue, changes)
+		}
+	}
+
+}
+
+// diffArrays compares elements by position; extra elements are added in order, or removed from the end,
+// so the indexes of a patch remain valid as it's applied
+func diffArrays(path string, a []interface{}, b []interface{}, changes *[]Change) {
+
+	common := len(a)
+	if len(b) < common {
+		common = len(b)
+	}
+
+	for i := 0; i < common; i++ {
+		diffValues(path+"/"+strconv.Itoa(i), a[i], b[i], changes)
+	}
+
+	for i := len(a) - 1; i >= common; i-- {
+		*changes = append(*changes, Change{Op: ChangeRemove, Path: path + "/" + strconv.Itoa(i), From: a[i]})
+	}
+
+	for i := common; i < len(b); i++ {
+		*changes = append(*changes, Change{Op: ChangeAdd, Path: path + "/" + strconv.Itoa(i), To: b[i]})
+	}
+
+}
+
+// escapePointer escapes a key for use in an RFC 6901 JSON pointer
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
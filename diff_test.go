@@ -0,0 +1,125 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+
+	a := Record{
+		ID:        "a",
+		Type:      "test",
+		Hostname:  "alpha",
+		IP:        "10.0.0.1",
+		Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
+		Context:   map[string]interface{}{"region": "pdx", "a/b": 1},
+		Data:      json.RawMessage(`{"cpus":2,"disks":[{"size":10},{"size":20},{"size":30}],"tags":["x"],"retired":false}`),
+	}
+
+	b := a
+	b.IP = "10.0.0.2"
+	b.Committer = "Test Monkey"
+	b.Context = map[string]interface{}{"region": "pdx", "a/b": 1.0}
+	b.Data = json.RawMessage(`{"cpus":2.0,"disks":[{"size":10},{"size":25}],"tags":["x","y","z"],"retired":null}`)
+
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "a", d.ID, "id")
+	assert.Equal(t, []Change{
+		{Op: ChangeAdd, Path: "/committer", To: "Test Monkey"},
+		{Op: ChangeReplace, Path: "/data/disks/1/size", From: json.Number("20"), To: json.Number("25")},
+		{Op: ChangeRemove, Path: "/data/disks/2", From: map[string]interface{}{"size": json.Number("30")}},
+		{Op: ChangeReplace, Path: "/data/retired", From: false, To: nil},
+		{Op: ChangeAdd, Path: "/data/tags/1", To: "y"},
+		{Op: ChangeAdd, Path: "/data/tags/2", To: "z"},
+		{Op: ChangeReplace, Path: "/ip", From: "10.0.0.1", To: "10.0.0.2"},
+	}, d.Changes, "path-level changes, ignoring number formatting")
+
+	patchBytes, err := json.Marshal(d.Patch())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.JSONEq(t, `[
+		{"op":"add","path":"/committer","value":"Test Monkey"},
+		{"op":"replace","path":"/data/disks/1/size","value":25},
+		{"op":"remove","path":"/data/disks/2"},
+		{"op":"replace","path":"/data/retired","value":null},
+		{"op":"add","path":"/data/tags/1","value":"y"},
+		{"op":"add","path":"/data/tags/2","value":"z"},
+		{"op":"replace","path":"/ip","value":"10.0.0.2"}
+	]`, string(patchBytes), "RFC 6902 JSON Patch")
+
+	// pointers are escaped
+	b = a
+	b.Context = map[string]interface{}{"region": "pdx", "a/b": 2}
+
+	d, err = Diff(a, b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, []Change{{Op: ChangeReplace, Path: "/context/a~1b", From: json.Number("1"), To: json.Number("2")}}, d.Changes, "escaped pointer")
+
+	// identical records
+	d, err = Diff(a, a)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Empty(t, d.Changes, "no changes")
+
+	// invalid data
+	_, err = Diff(a, Record{Data: json.RawMessage(`{`)})
+	assert.Error(t, err, "invalid data")
+
+}
+
+func TestDiffRecordSets(t *testing.T) {
+
+	a := RecordSet{
+		Type:      "test",
+		Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
+		Context:   map[string]interface{}{"region": "pdx"},
+		Records: []Record{
+			{ID: "a", Hostname: "alpha", IP: "10.0.0.1"},
+			{ID: "b", Hostname: "bravo", IP: "10.0.0.2"},
+			{ID: "c", Hostname: "charlie", IP: "10.0.0.3"},
+			{Hostname: "delta", IP: "10.0.0.4", Context: map[string]interface{}{"region": "pdx", "zone": "a"}},
+			{Hostname: "echo", IP: "10.0.0.5"},
+		},
+	}
+
+	b := RecordSet{
+		Type:      "test",
+		Timestamp: MustParseTimestamp("2003-04-06 06:07:08"),
+		Context:   map[string]interface{}{"region": "pdx"},
+		Records: []Record{
+			{ID: "a", Hostname: "alpha", IP: "10.0.0.1", Timestamp: MustParseTimestamp("2003-04-06 06:07:08")},
+			{ID: "b", Hostname: "bravo", IP: "10.0.0.20"},
+			{Type: "test", Hostname: "delta", IP: "10.0.0.4", Context: map[string]interface{}{"zone": "a"}},
+			{ID: "f", Hostname: "foxtrot", IP: "10.0.0.6"},
+		},
+	}
+
+	d, err := DiffRecordSets(a, b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 2, d.Unchanged, "matched by ID, ignoring the timestamp, and matched by hash, with the context of the set")
+	assert.Equal(t, []Record{a.Records[2], a.Records[4]}, d.Removed, "removed")
+	assert.Equal(t, []Record{b.Records[3]}, d.Added, "added")
+
+	if assert.Len(t, d.Modified, 1, "modified") {
+		assert.Equal(t, "b", d.Modified[0].ID, "modified ID")
+		assert.Equal(t, []Change{{Op: ChangeReplace, Path: "/ip", From: "10.0.0.2", To: "10.0.0.20"}}, d.Modified[0].Changes, "modified changes")
+	}
+
+}
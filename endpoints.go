@@ -0,0 +1,87 @@
+package hostdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Catalog will retrieve the distinct values of a field (e.g. hostname)
+func (c *Client) Catalog(field string) (GetCatalogResponse, error) {
+	return c.CatalogContext(context.Background(), field)
+}
+
+// CatalogContext will retrieve the distinct values of a field, until ctx is canceled
+func (c *Client) CatalogContext(ctx context.Context, field string) (response GetCatalogResponse, err error) {
+
+	if field == "" {
+		return response, errors.New("a catalog field is required")
+	}
+
+	err = c.get(ctx, fmt.Sprintf("/catalog/%s", url.PathEscape(field)), &response)
+
+	return response, err
+
+}
+
+// CatalogQuantity will retrieve the distinct values of a field, with the number of records for each
+func (c *Client) CatalogQuantity(field string) (GetCatalogQuantityResponse, error) {
+	return c.CatalogQuantityContext(context.Background(), field)
+}
+
+// CatalogQuantityContext will retrieve the distinct values of a field, with the number of records for each,
+// until ctx is canceled
+func (c *Client) CatalogQuantityContext(ctx context.Context, field string) (response GetCatalogQuantityResponse, err error) {
+
+	if field == "" {
+		return response, errors.New("a catalog field is required")
+	}
+
+	err = c.get(ctx, fmt.Sprintf("/catalog/%s?count=true", url.PathEscape(field)), &response)
+
+	return response, err
+
+}
+
+// Health will retrieve the health of HostDB and its database
+func (c *Client) Health() (GetHealthResponse, error) {
+	return c.HealthContext(context.Background())
+}
+
+// HealthContext will retrieve the health of HostDB and its database, until ctx is canceled
+func (c *Client) HealthContext(ctx context.Context) (response GetHealthResponse, err error) {
+
+	err = c.get(ctx, "/health", &response)
+
+	return response, err
+
+}
+
+// Stats will retrieve statistics about the records in HostDB
+func (c *Client) Stats() (GetStatsResponse, error) {
+	return c.StatsContext(context.Background())
+}
+
+// StatsContext will retrieve statistics about the records in HostDB, until ctx is canceled
+func (c *Client) StatsContext(ctx context.Context) (response GetStatsResponse, err error) {
+
+	err = c.get(ctx, "/stats", &response)
+
+	return response, err
+
+}
+
+// Version will retrieve the versions of HostDB and its database
+func (c *Client) Version() (GetVersionResponse, error) {
+	return c.VersionContext(context.Background())
+}
+
+// VersionContext will retrieve the versions of HostDB and its database, until ctx is canceled
+func (c *Client) VersionContext(ctx context.Context) (response GetVersionResponse, err error) {
+
+	err = c.get(ctx, "/version", &response)
+
+	return response, err
+
+}
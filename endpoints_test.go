@@ -0,0 +1,84 @@
+package hostdb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Endpoints(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+
+		switch r.URL.Path {
+		case "/catalog/hostname":
+			if r.URL.Query().Get("count") != "" {
+				body = "{\"count\":2,\"query_time\":\"1ms\",\"catalog\":{\"a.local\":1,\"b.local\":3}}"
+			} else {
+				body = "{\"count\":2,\"query_time\":\"1ms\",\"catalog\":[\"a.local\",\"b.local\"]}"
+			}
+		case "/health":
+			body = "{\"app\":\"ok\",\"db\":\"ok\"}"
+		case "/stats":
+			body = "{\"hostname\":\"hostdb-1\",\"total_records\":4,\"lastseen_collectors\":{\"test\":\"2003-04-05 06:07:08\"}}"
+		case "/version":
+			body = "{\"app\":{\"version\":\"1.2.3\",\"api_version\":\"0\"},\"db\":{\"version\":\"10.4\"}}"
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			body = "{\"error\":\"something broke\"}"
+		}
+
+		if _, err := fmt.Fprintln(w, body); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	catalog, err := client.Catalog("hostname")
+	if assert.NoError(t, err, "catalog") {
+		assert.Equal(t, []string{"a.local", "b.local"}, catalog.Catalog, "catalog")
+	}
+
+	quantity, err := client.CatalogQuantity("hostname")
+	if assert.NoError(t, err, "catalog quantity") {
+		assert.Equal(t, 3, quantity.Catalog["b.local"], "catalog quantity")
+	}
+
+	health, err := client.Health()
+	if assert.NoError(t, err, "health") {
+		assert.Equal(t, GetHealthResponse{App: "ok", DB: "ok"}, health, "health")
+	}
+
+	stats, err := client.Stats()
+	if assert.NoError(t, err, "stats") {
+		assert.Equal(t, 4, stats.TotalRecords, "total records")
+		assert.Equal(t, "2003-04-05 06:07:08", stats.LastSeenCollectors["test"], "last seen collectors")
+	}
+
+	version, err := client.Version()
+	if assert.NoError(t, err, "version") {
+		assert.Equal(t, "1.2.3", version.App.Version, "app version")
+		assert.Equal(t, "10.4", version.DB.Version, "db version")
+	}
+
+	// errors are surfaced consistently
+	_, err = client.Catalog("unknown")
+
+	var errorResponse ErrorResponse
+	assert.True(t, errors.As(err, &errorResponse), "error response")
+	assert.Equal(t, "something broke", errorResponse.Message, "error message")
+
+	_, err = client.Catalog("")
+	assert.Error(t, err, "missing field")
+
+}
@@ -0,0 +1,117 @@
+package hostdb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxDecompressedBytes is the largest request body which DecompressRequest will decode
+const DefaultMaxDecompressedBytes = 64 << 20
+
+// DecompressedBodyTooLargeError is returned when reading a request body, decoded by DecompressRequest,
+// beyond its limit; handlers should respond with a 413
+type DecompressedBodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *DecompressedBodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body is larger than %d bytes, when decompressed", e.Limit)
+}
+
+// DecompressRequest is middleware for servers, which will transparently decode
+// request bodies sent with Content-Encoding: gzip. Other requests pass through untouched.
+// A body without a valid gzip header is rejected with a 400 and a GenericError. The body is
+// decoded as it's read, and reading more than DefaultMaxDecompressedBytes from it returns a
+// *DecompressedBodyTooLargeError.
+func DecompressRequest(next http.Handler) http.Handler {
+	return DecompressRequestLimit(next, DefaultMaxDecompressedBytes)
+}
+
+// DecompressRequestLimit is like DecompressRequest, but limits the decoded body to maxBytes
+func DecompressRequestLimit(next http.Handler, maxBytes int64) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if !strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeGenericError(w, http.StatusBadRequest, "invalid gzip request body")
+			return
+		}
+
+		r.Body = &decompressedBody{reader: reader, body: r.Body, limit: maxBytes, remaining: maxBytes}
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+
+		next.ServeHTTP(w, r)
+
+	})
+
+}
+
+// decompressedBody decodes a gzip request body as it's read, up to a limit
+type decompressedBody struct {
+	reader    *gzip.Reader
+	body      io.Closer
+	limit     int64
+	remaining int64
+	err       error
+}
+
+func (b *decompressedBody) Read(p []byte) (n int, err error) {
+
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	if len(p) < 1 {
+		return 0, nil
+	}
+
+	// read one byte beyond the limit, to tell whether it's been exceeded
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err = b.reader.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		b.err = err
+		return n, err
+	}
+
+	n = int(b.remaining)
+	b.remaining = 0
+	b.err = &DecompressedBodyTooLargeError{Limit: b.limit}
+
+	return n, b.err
+
+}
+
+func (b *decompressedBody) Close() error {
+
+	if err := b.reader.Close(); err != nil {
+		_ = b.body.Close()
+		return err
+	}
+
+	return b.body.Close()
+
+}
+
+func writeGenericError(w http.ResponseWriter, status int, message string) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(GenericError{Error: message})
+
+}
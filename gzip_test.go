@@ -0,0 +1,108 @@
+package hostdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Gzip(t *testing.T) {
+
+	// fake http server, which decompresses with the middleware
+	var received RecordSet
+	var encoding string
+	handler := DecompressRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err.Error())
+		}
+
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// capture the encoding before the middleware removes it
+		encoding = r.Header.Get("Content-Encoding")
+		handler.ServeHTTP(w, r)
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Gzip: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "gzip", encoding, "content encoding")
+	assert.Equal(t, TestRecordSet.Committer, received.Committer, "decompressed record set")
+
+}
+
+func TestDecompressRequest(t *testing.T) {
+
+	handler := DecompressRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+
+		var tooLarge *DecompressedBodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			t.Error(err.Error())
+		}
+
+		if _, err := w.Write(body); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+
+	// uncompressed requests pass through
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/records/", strings.NewReader("{}")))
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "uncompressed status")
+	assert.Equal(t, "{}", recorder.Body.String(), "uncompressed body")
+
+	// invalid gzip bodies are rejected
+	request := httptest.NewRequest(http.MethodPost, "/records/", strings.NewReader("not gzip"))
+	request.Header.Set("Content-Encoding", "gzip")
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "invalid gzip status")
+
+	// bodies which decompress beyond the limit can't be read
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(make([]byte, 1<<20)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for limit, status := range map[int64]int{1 << 20: http.StatusOK, 1<<20 - 1: http.StatusRequestEntityTooLarge} {
+		request = httptest.NewRequest(http.MethodPost, "/records/", bytes.NewReader(compressed.Bytes()))
+		request.Header.Set("Content-Encoding", "gzip")
+
+		recorder = httptest.NewRecorder()
+		DecompressRequestLimit(handler, limit).ServeHTTP(recorder, request)
+
+		assert.Equal(t, status, recorder.Code, "status with a limit of %d bytes", limit)
+	}
+
+}
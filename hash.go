@@ -0,0 +1,137 @@
+package hostdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// ComputeHash returns a SHA-256 digest of the canonical JSON of the record's Type, Hostname, IP, Addresses, Context and Data.
+// Volatile fields, such as ID, Timestamp and Committer, are excluded, so unchanged hosts keep the same hash.
+func (r Record) ComputeHash() (string, error) {
+
+	document := map[string]interface{}{
+		"type":     r.Type,
+		"hostname": r.Hostname,
+		"ip":       r.IP,
+	}
+
+	if len(r.Addresses) > 0 {
+		document["addresses"] = sortedAddresses(r.Addresses)
+	}
+
+	if len(r.Context) > 0 {
+		document["context"] = r.Context
+	}
+
+	if len(r.Data) > 0 {
+		var data interface{}
+		if err := unmarshalNumbers(r.Data, &data); err != nil {
+			return "", err
+		}
+
+		if data != nil {
+			document["data"] = data
+		}
+	}
+
+	canonicalBytes, err := canonicalJSON(document)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonicalBytes)
+
+	return hex.EncodeToString(sum[:]), nil
+
+}
+
+// WithHashes returns a copy of the record set, with the Hash of every record computed.
+// Records are hashed with the Type and Context they inherit from the set, as that's what the server will store.
+func (rs RecordSet) WithHashes() (RecordSet, error) {
+
+	records := make([]Record, len(rs.Records))
+
+	for i, r := range rs.Records {
+		hash, err := rs.inherit(r).ComputeHash()
+		if err != nil {
+			return rs, err
+		}
+
+		r.Hash = hash
+		records[i] = r
+	}
+
+	rs.Records = records
+
+	return rs, nil
+
+}
+
+// canonicalJSON encodes v with sorted object keys, normalized numbers, and no insignificant whitespace
+func canonicalJSON(v interface{}) ([]byte, error) {
+
+	// round-trip through JSON, so structs, maps and raw messages are all treated alike
+	valueBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := unmarshalNumbers(valueBytes, &value); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+
+	// maps are encoded with sorted keys
+	if err := encoder.Encode(normalizeNumbers(value)); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+
+}
+
+// unmarshalNumbers decodes JSON, keeping numbers as json.Number
+func unmarshalNumbers(data []byte, v interface{}) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+
+}
+
+// normalizeNumbers rewrites every json.Number in its shortest form, so 1, 1.0 and 1e0 are equal
+func normalizeNumbers(v interface{}) interface{} {
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, element := range value {
+			value[k] = normalizeNumbers(element)
+		}
+	case []interface{}:
+		for i, element := range value {
+			value[i] = normalizeNumbers(element)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(i, 10))
+		}
+
+		if f, err := strconv.ParseFloat(value.String(), 64); err == nil {
+			if f >= -1<<63 && f < 1<<63 && f == float64(int64(f)) {
+				return json.Number(strconv.FormatInt(int64(f), 10))
+			}
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+
+	return v
+
+}
@@ -0,0 +1,136 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord_ComputeHash(t *testing.T) {
+
+	record := Record{
+		ID:        "a",
+		Type:      "test",
+		Hostname:  "alpha",
+		IP:        "10.0.0.1",
+		Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
+		Committer: "Test Monkey",
+		Context:   map[string]interface{}{"b": 1, "a": []interface{}{"x", 2.5}},
+		Data:      json.RawMessage(`{"z": {"y": 1.0, "x": true}, "w": 1e2}`),
+	}
+
+	hash, err := record.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, hash, 64, "hex encoded SHA-256")
+
+	// key order, whitespace, number formatting and volatile fields don't matter
+	equivalent := Record{
+		Type:      "test",
+		Hostname:  "alpha",
+		IP:        "10.0.0.1",
+		Timestamp: MustParseTimestamp("2010-01-01 00:00:00"),
+		Context:   map[string]interface{}{"a": []interface{}{"x", 2.5}, "b": 1.0},
+		Data:      json.RawMessage(`{"w":100,"z":{"x":true,"y":1}}`),
+	}
+
+	equivalentHash, err := equivalent.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, hash, equivalentHash, "canonical hash")
+
+	// anything else does
+	changed := equivalent
+	changed.Data = json.RawMessage(`{"w":101,"z":{"x":true,"y":1}}`)
+
+	changedHash, err := changed.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.NotEqual(t, hash, changedHash, "changed data")
+
+	// empty context and data are the same as none
+	emptyHash, err := Record{Type: "test", Context: map[string]interface{}{}, Data: json.RawMessage{}}.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	noneHash, err := Record{Type: "test", Data: json.RawMessage("null")}.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, noneHash, emptyHash, "empty context and data")
+
+	// invalid data
+	_, err = Record{Data: json.RawMessage(`{`)}.ComputeHash()
+	assert.Error(t, err, "invalid data")
+
+}
+
+func TestCanonicalJSON(t *testing.T) {
+
+	canonicalBytes, err := canonicalJSON(map[string]interface{}{
+		"b": json.RawMessage(`[1.50, 2E3, -0.0, 12345678901234567890]`),
+		"a": "<&>",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"a":"<&>","b":[1.5,2000,0,1.2345678901234567e+19]}`, string(canonicalBytes), "canonical JSON")
+
+}
+
+func TestRecordSet_HashRecords(t *testing.T) {
+
+	var received RecordSet
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err.Error())
+		}
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rs := RecordSet{
+		Type:        "test",
+		Context:     map[string]interface{}{"region": "pdx"},
+		Records:     []Record{{Hostname: "alpha"}, {Hostname: "bravo"}},
+		HashRecords: true,
+	}
+
+	if err := client.SendRecordSet(rs, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected, err := Record{Type: "test", Hostname: "alpha", Context: map[string]interface{}{"region": "pdx"}}.ComputeHash()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if assert.Len(t, received.Records, 2, "records") {
+		assert.Equal(t, expected, received.Records[0].Hash, "hashed with the type and context of the set")
+		assert.NotEqual(t, received.Records[0].Hash, received.Records[1].Hash, "distinct hashes")
+		assert.Equal(t, "", received.Records[0].Type, "type isn't otherwise changed")
+	}
+
+	assert.Equal(t, "", rs.Records[0].Hash, "the caller's records are untouched")
+
+}
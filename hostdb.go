@@ -1,21 +1,18 @@
 package hostdb
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 )
 
-// ErrorResponse is used for any requests which result in an error
+// ErrorResponse is used for any requests which result in an error.
+// Requests to HostDB which receive an unsuccessful status will return an
+// ErrorResponse, which can be retrieved with errors.As
 type ErrorResponse struct {
 	Code    int
 	Message string
@@ -25,6 +22,19 @@ func (e ErrorResponse) Error() string {
 	return fmt.Sprintf("%v: %v", e.Code, e.Message)
 }
 
+// DeleteRecordResponse is used when responding to single-record DELETE requests
+type DeleteRecordResponse struct {
+	ID string `json:"id"`
+	OK bool   `json:"ok"`
+}
+
+// DeleteRecordsResponse is used when responding to DELETE requests for many records
+type DeleteRecordsResponse struct {
+	Count int    `json:"count"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
 // GenericError is used for all sorts of things
 type GenericError struct {
 	Error string `json:"error"`
@@ -82,6 +92,12 @@ type GlobalConfig struct {
 	} `json:"api" mapstructure:"api"`
 }
 
+// PatchRecordResponse is used when responding to single-record PATCH requests
+type PatchRecordResponse struct {
+	ID string `json:"id"`
+	OK bool   `json:"ok"`
+}
+
 // PostRecordsResponse is used to respond to POST requests
 type PostRecordsResponse struct {
 	OK    bool   `json:"ok"`
@@ -99,58 +115,78 @@ type Record struct {
 	ID        string                 `json:"id,omitempty"`
 	Type      string                 `json:"type,omitempty"`
 	Hostname  string                 `json:"hostname,omitempty"`
-	IP        string                 `json:"ip,omitempty"`
-	Timestamp string                 `json:"timestamp,omitempty"`
+	IP        string                 `json:"ip,omitempty"` // the primary address
+	Addresses []Address              `json:"addresses,omitempty"`
+	Timestamp Timestamp              `json:"timestamp,omitempty"`
 	Committer string                 `json:"committer,omitempty"`
 	Context   map[string]interface{} `json:"context,omitempty"`
 	Data      json.RawMessage        `json:"data,omitempty"`
 	Hash      string                 `json:"hash,omitempty"`
 }
 
-// Send will PUT a single record into HostDB
+// recordJSON has the fields of a Record, without its MarshalJSON method
+type recordJSON Record
+
+// MarshalJSON encodes the record, leaving out an unset Timestamp (which omitempty can't do for a struct),
+// so the record takes the timestamp of its set rather than being sent with a null one
+func (r Record) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(struct {
+		recordJSON
+		Timestamp *Timestamp `json:"timestamp,omitempty"`
+	}{
+		recordJSON: recordJSON(r),
+		Timestamp:  optionalTimestamp(r.Timestamp),
+	})
+
+}
+
+// Send will PUT a single record into HostDB, using the default client
 func (r Record) Send(uniqueIdentifier string) (err error) {
+	return r.SendContext(context.Background(), uniqueIdentifier)
+}
 
-	// ensure we have a unique identifier, which is used when viewing logs
-	if uniqueIdentifier == "" {
-		uniqueIdentifier = r.Type
-	}
+// SendContext will PUT a single record into HostDB, using the default client, until ctx is canceled
+func (r Record) SendContext(ctx context.Context, uniqueIdentifier string) (err error) {
 
-	// post data to HostDB
-	responseBytes, err := httpRequest(
-		"PUT",
-		fmt.Sprintf("/records/%s", r.ID),
-		r,
-		nil,
-	)
+	client, err := getDefaultClient()
 	if err != nil {
-		log.Println(fmt.Sprintf("%s", string(responseBytes)))
-		return err
-	}
-
-	// unmarshal the response into a struct
-	var response PutRecordResponse
-	if err := json.Unmarshal(responseBytes, &response); err != nil {
 		return err
 	}
 
-	// if something's not OK, return an error
-	if response.OK != true {
-		return fmt.Errorf("failed to save record %s", response.ID)
-	}
-
-	log.Println(fmt.Sprintf("1 %s record sent to HostDB", r.Type))
-
-	return nil
+	return client.SendRecordContext(ctx, r, uniqueIdentifier)
 
 }
 
 // RecordSet is a collection of similar records
 type RecordSet struct {
 	Type      string                 `json:"type"`
-	Timestamp string                 `json:"timestamp"`
+	Timestamp Timestamp              `json:"timestamp"`
 	Context   map[string]interface{} `json:"context"`
 	Committer string                 `json:"committer,omitempty"`
 	Records   []Record               `json:"records"`
+
+	// when set, the Hash of every record is computed before the set is sent
+	HashRecords bool `json:"-"`
+}
+
+// recordSetJSON has the fields of a RecordSet, without its MarshalJSON method
+type recordSetJSON RecordSet
+
+// MarshalJSON encodes the record set, leaving out an unset Timestamp, as for a Record.
+// The records are declared again, so they remain the last field, which EncodeJSON relies on.
+func (rs RecordSet) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(struct {
+		recordSetJSON
+		Timestamp *Timestamp `json:"timestamp,omitempty"`
+		Records   []Record   `json:"records"`
+	}{
+		recordSetJSON: recordSetJSON(rs),
+		Timestamp:     optionalTimestamp(rs.Timestamp),
+		Records:       rs.Records,
+	})
+
 }
 
 // Save will write a JSON file to disk, with what would be submitted to HostDB.
@@ -164,74 +200,72 @@ func (rs RecordSet) Save(filePath string) (err error) {
 		return errors.New("provided file path must end in .json")
 	}
 
-	// convert the struct into bytes
-	requestBytes, err := json.Marshal(rs)
-	if err != nil {
-		return err
-	}
-
 	// let the user know we're starting
-	log.Println(fmt.Sprintf("saving %d records into %s", len(rs.Records), filePath))
+	getLogger().Info("saving record set", Fields{"type": rs.Type, "records": len(rs.Records), "file": filePath})
 
 	// ensure path exists for output
 	if _, err := os.Stat(filepath.Dir(filePath)); os.IsNotExist(err) {
 		if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
 
-	// output to file
-	if err := ioutil.WriteFile(filePath, requestBytes, 0644); err != nil {
-		log.Fatal(err)
+	// stream the records into a temporary file, which replaces the output once complete
+	file, err := ioutil.TempFile(filepath.Dir(filePath), fmt.Sprintf(".%s.*", filepath.Base(filePath)))
+	if err != nil {
+		return err
+	}
+
+	if err := writeRecordSetFile(file, rs); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), filePath); err != nil {
+		_ = os.Remove(file.Name())
+		return err
 	}
 
 	// let the user know we're done
-	log.Println(fmt.Sprintf("saved %d records into %s", len(rs.Records), filePath))
+	getLogger().Info("saved record set", Fields{"type": rs.Type, "records": len(rs.Records), "file": filePath})
 
 	return nil
 
 }
 
-// Send will post the RecordSet to HostDB, if credentials are present.
+// writeRecordSetFile will encode the RecordSet into file, and close it
+func writeRecordSetFile(file *os.File, rs RecordSet) (err error) {
+
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := file.Chmod(0644); err != nil {
+		return err
+	}
+
+	return rs.EncodeJSON(file)
+
+}
+
+// Send will post the RecordSet to HostDB, using the default client, if credentials are present.
 // The input variable uniqueQueryString is used when viewing traffic logs --
 // it has no impact on function
 func (rs RecordSet) Send(uniqueQueryString string) (err error) {
+	return rs.SendContext(context.Background(), uniqueQueryString)
+}
 
-	// ensure we have a unique identifier, which is used when viewing logs
-	if uniqueQueryString == "" {
-		uniqueQueryString = fmt.Sprintf("?type=%s", rs.Type)
-	}
+// SendContext will post the RecordSet to HostDB, using the default client, until ctx is canceled
+func (rs RecordSet) SendContext(ctx context.Context, uniqueQueryString string) (err error) {
 
-	// let the user know we're starting
-	log.Println(fmt.Sprintf("sending %d %v record(s) to HostDB", len(rs.Records), rs.Type))
-
-	// post data to HostDB
-	responseBytes, err := httpRequest(
-		"POST",
-		fmt.Sprintf("/records/?%s", uniqueQueryString),
-		rs,
-		nil,
-	)
+	client, err := getDefaultClient()
 	if err != nil {
-		log.Println(fmt.Sprintf("%s", string(responseBytes)))
-		return err
-	}
-
-	// unmarshal the response into a struct
-	var response PostRecordsResponse
-	if err := json.Unmarshal(responseBytes, &response); err != nil {
 		return err
 	}
 
-	// if something's not OK, return an error
-	if response.OK != true {
-		return errors.New(response.Error)
-	}
-
-	// let the user know we're done
-	log.Println(fmt.Sprintf("sent %d %v record(s) to HostDB", len(rs.Records), rs.Type))
-
-	return nil
+	return client.SendRecordSetContext(ctx, rs, uniqueQueryString)
 
 }
 
@@ -256,87 +290,33 @@ type ServerVersion struct {
 	GoVersion  string `json:"go_version"`
 }
 
-func httpRequest(method string, path string, requestBody interface{}, header map[string]string) (responseBytes []byte, err error) {
-
-	// hostdbURL
-	hostdbURL, found := os.LookupEnv("HOSTDB_URL")
-	if !found || hostdbURL == "" {
-		hostdbURL = "https://hostdb.pdxfixit.com/v0"
-	} else {
-		if _, err := url.ParseRequestURI(hostdbURL); err != nil {
-			log.Fatal("HOSTDB_URL is invalid")
-		}
-	}
-	hostdbURL = hostdbURL + path
-
-	// hostdbUser
-	hostdbUser, found := os.LookupEnv("HOSTDB_USER")
-	if !found || hostdbUser == "" {
-		hostdbUser = "writer"
-	}
-
-	// hostdbPass
-	hostdbPass := os.Getenv("HOSTDB_PASS")
+// inherit returns a copy of the record, with the Type, Timestamp and Committer of the set if it has none,
+// and the Context of the set merged beneath its own
+func (rs RecordSet) inherit(r Record) Record {
 
-	// if no password, return
-	if hostdbPass == "" {
-		log.Println("no password, canceling request")
-		return []byte(`{"OK":true,"error":"no password, canceling request"}`), nil
+	if r.Type == "" {
+		r.Type = rs.Type
 	}
 
-	//log.Println(fmt.Sprintf("using ***%s : ***%s @ %s", hostdbUser[len(hostdbUser)-3:], hostdbPass[len(hostdbPass)-3:], hostdbURL))
-
-	// encode creds for basic auth
-	if len(header) < 1 {
-		header = make(map[string]string)
+	if r.Timestamp.IsZero() {
+		r.Timestamp = rs.Timestamp
 	}
-	header["Authorization"] = fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
-		"%s:%s", hostdbUser, hostdbPass,
-	))))
 
-	// convert the struct into bytes
-	requestBytes, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
+	if r.Committer == "" {
+		r.Committer = rs.Committer
 	}
 
-	// Client
-	client := &http.Client{}
-	req, err := http.NewRequest(method, hostdbURL, bytes.NewReader(requestBytes))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// INSECURE
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-
-	// headers
-	if len(header) > 0 {
-		for k, v := range header {
-			req.Header.Add(k, v)
+	if len(rs.Context) > 0 {
+		merged := make(map[string]interface{}, len(rs.Context)+len(r.Context))
+		for k, v := range rs.Context {
+			merged[k] = v
 		}
+		for k, v := range r.Context {
+			merged[k] = v
+		}
+		r.Context = merged
 	}
 
-	var res *http.Response
-	res, err = client.Do(req)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	responseBytes, err = ioutil.ReadAll(res.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = res.Body.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	if res.StatusCode != 200 {
-		return responseBytes, errors.New(res.Status)
-	}
-
-	return responseBytes, nil
+	return r
 
 }
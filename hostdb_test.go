@@ -7,17 +7,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 var TestRecord = Record{
-	ID:        "",
+	ID:        "test",
 	Type:      "",
 	Hostname:  "",
 	IP:        "",
-	Timestamp: "",
+	Timestamp: Timestamp{},
 	Committer: "",
 	Context:   map[string]interface{}{},
 	Data:      json.RawMessage{},
@@ -26,7 +27,7 @@ var TestRecord = Record{
 
 var TestRecordSet = RecordSet{
 	Type:      "test",
-	Timestamp: "2003-04-05 06:07:08",
+	Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
 	Context: map[string]interface{}{
 		"test": true,
 	},
@@ -105,6 +106,10 @@ func TestRecordSet_Save(t *testing.T) {
 	}
 
 	assert.Equal(t, requestBytes, fileBytes, "comparing request to file")
+
+	// a directory which can't be created is an error, rather than being fatal
+	assert.Error(t, TestRecordSet.Save("hostdb_test.go/test.json"), "saving beneath a file")
+
 }
 
 func TestRecordSet_Send(t *testing.T) {
@@ -151,8 +156,44 @@ func TestRecordSet_Send_WithNoCreds(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
+	assert.Equal(t, ErrMissingCredentials, TestRecordSet.Send("test"), "missing credentials")
+
+}
+
+func TestRecordSet_Send_DryRun(t *testing.T) {
+
+	dir := t.TempDir()
+
+	if err := os.Setenv("HOSTDB_DRY_RUN", "true"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := os.Setenv("HOSTDB_DRY_RUN_DIR", dir); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer func() {
+		if err := os.Unsetenv("HOSTDB_DRY_RUN"); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if err := os.Unsetenv("HOSTDB_DRY_RUN_DIR"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
 	if err := TestRecordSet.Send("test"); err != nil {
 		t.Errorf("%v", err)
 	}
 
+	assert.FileExists(t, filepath.Join(dir, "test.json"), "dry run saves the payload")
+
+	// the default logger discards everything, so payloads are also written to stderr
+	client, err := getDefaultClient()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, os.Stderr, client.config.DryRunWriter, "dry run reports to stderr")
+
 }
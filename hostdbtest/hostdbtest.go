@@ -0,0 +1,717 @@
+// Package hostdbtest provides an in-memory fake HostDB server, for testing collectors and other clients.
+//
+// The server implements the v0 records, catalog, stats, health and version endpoints,
+// records every request it receives, and can be told to fail or slow down.
+package hostdbtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pdxfixit/hostdb"
+)
+
+// Pass is the password given to clients created by Server.Client
+const Pass = "hostdbtest"
+
+// Request is a request received by the Server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte // after any gzip Content-Encoding has been removed
+	Time   time.Time
+}
+
+// Failure describes requests which should fail, and how
+type Failure struct {
+	Method string // optional; defaults to all methods
+	Path   string // optional; a prefix such as /records/, defaults to all paths
+
+	Status  int    // optional; defaults to 500
+	Message string // optional; returned as a GenericError
+
+	// the number of requests to fail; zero fails every matching request, until Reset
+	Count int
+}
+
+// Server is a fake HostDB, backed by an in-memory store
+type Server struct {
+	*httptest.Server
+
+	// returned by the version endpoint
+	Version hostdb.GetVersionResponse
+
+	mutex    sync.Mutex
+	records  map[string]hostdb.Record
+	requests []Request
+	failures []*Failure
+	latency  time.Duration
+}
+
+// NewServer will start a new fake HostDB; it should be closed when no longer needed
+func NewServer() *Server {
+
+	s := &Server{
+		Version: hostdb.GetVersionResponse{
+			App: hostdb.ServerVersion{
+				Version:    "hostdbtest",
+				APIVersion: "v0",
+				GoVersion:  runtime.Version(),
+			},
+			DB: hostdb.MariadbVersion{Version: "in-memory"},
+		},
+		records: map[string]hostdb.Record{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/records/", s.handleRecords)
+	mux.HandleFunc("/catalog/", s.handleCatalog)
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/version", s.handleVersion)
+
+	s.Server = httptest.NewServer(hostdb.DecompressRequest(s.intercept(mux)))
+
+	return s
+
+}
+
+// Client will return a new client for the server; the URL is always replaced,
+// and the password is set unless the config already has credentials
+func (s *Server) Client(config hostdb.ClientConfig) (*hostdb.Client, error) {
+
+	config.URL = s.URL
+
+	if config.Pass == "" && config.Auth == nil {
+		config.Pass = Pass
+	}
+
+	return hostdb.NewClient(config)
+
+}
+
+// AddRecords will store the records, as if they had been PUT
+func (s *Server) AddRecords(records ...hostdb.Record) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, r := range records {
+		if r.ID == "" {
+			r.ID = recordID(r)
+		}
+		s.records[r.ID] = r
+	}
+
+}
+
+// Record returns the stored record with the given ID
+func (s *Server) Record(id string) (hostdb.Record, bool) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, found := s.records[id]
+
+	return r, found
+
+}
+
+// Records returns every stored record, sorted by ID
+func (s *Server) Records() []hostdb.Record {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.sortedRecords(nil)
+
+}
+
+// Requests returns every request received, in order
+func (s *Server) Requests() []Request {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Request(nil), s.requests...)
+
+}
+
+// InjectFailure will cause matching requests to fail, before they reach the store
+func (s *Server) InjectFailure(f Failure) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if f.Status == 0 {
+		f.Status = http.StatusInternalServerError
+	}
+
+	if f.Message == "" {
+		f.Message = http.StatusText(f.Status)
+	}
+
+	s.failures = append(s.failures, &f)
+
+}
+
+// SetLatency will delay every response by d
+func (s *Server) SetLatency(d time.Duration) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.latency = d
+
+}
+
+// Reset will remove every record, request, failure and the latency
+func (s *Server) Reset() {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records = map[string]hostdb.Record{}
+	s.requests = nil
+	s.failures = nil
+	s.latency = 0
+
+}
+
+// intercept records each request, then applies any latency and failure
+func (s *Server) intercept(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		body, err := ioutil.ReadAll(r.Body)
+
+		var tooLarge *hostdb.DecompressedBodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+
+		s.mutex.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+			Time:   time.Now(),
+		})
+		latency := s.latency
+		failure := s.failure(r)
+		s.mutex.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if failure != nil {
+			writeError(w, failure.Status, failure.Message)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+
+	})
+
+}
+
+// failure returns the first failure matching the request, counting it down; the mutex must be held
+func (s *Server) failure(r *http.Request) *Failure {
+
+	for i, f := range s.failures {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+
+		matched := *f
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+
+		return &matched
+	}
+
+	return nil
+
+}
+
+func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
+
+	id := strings.TrimPrefix(r.URL.Path, "/records/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		s.getRecords(w, r)
+	case r.Method == http.MethodGet:
+		s.getRecord(w, id)
+	case r.Method == http.MethodPost && id == "":
+		s.postRecords(w, r)
+	case r.Method == http.MethodPut && id != "":
+		s.putRecord(w, r, id)
+	case r.Method == http.MethodPatch && id != "":
+		s.patchRecord(w, r, id)
+	case r.Method == http.MethodDelete && id == "":
+		s.deleteRecords(w, r)
+	case r.Method == http.MethodDelete:
+		s.deleteRecord(w, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+}
+
+func (s *Server) getRecords(w http.ResponseWriter, r *http.Request) {
+
+	start := time.Now()
+	query := r.URL.Query()
+
+	limit, offset, err := paging(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	records := s.sortedRecords(query)
+	s.mutex.Unlock()
+
+	response := hostdb.GetRecordsResponse{Count: len(records), Records: map[string]hostdb.Record{}}
+
+	for i := offset; i < len(records) && (limit < 1 || i < offset+limit); i++ {
+		response.Records[records[i].ID] = records[i]
+	}
+
+	response.QueryTime = time.Since(start).String()
+
+	writeJSON(w, http.StatusOK, response)
+
+}
+
+func (s *Server) getRecord(w http.ResponseWriter, id string) {
+
+	r, found := s.Record(id)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("record %s not found", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, r)
+
+}
+
+// postRecords replaces the records with the same type and context as the record set
+func (s *Server) postRecords(w http.ResponseWriter, r *http.Request) {
+
+	var rs hostdb.RecordSet
+	if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+		writeJSON(w, http.StatusBadRequest, hostdb.PostRecordsResponse{Error: err.Error()})
+		return
+	}
+
+	if rs.Type == "" {
+		writeJSON(w, http.StatusBadRequest, hostdb.PostRecordsResponse{Error: "a record set type is required"})
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// a set replaces the records with the same type and context, unless it's being appended
+	if appendRecords, _ := strconv.ParseBool(r.URL.Query().Get(hostdb.QueryParamAppend)); !appendRecords {
+		for id, existing := range s.records {
+			if existing.Type == rs.Type && containsContext(existing.Context, rs.Context) {
+				delete(s.records, id)
+			}
+		}
+	}
+
+	for _, record := range rs.Records {
+		if record.Type == "" {
+			record.Type = rs.Type
+		}
+
+		if record.Timestamp.IsZero() {
+			record.Timestamp = rs.Timestamp
+		}
+
+		if record.Committer == "" {
+			record.Committer = rs.Committer
+		}
+
+		if len(rs.Context) > 0 {
+			context := map[string]interface{}{}
+			for k, v := range rs.Context {
+				context[k] = v
+			}
+			for k, v := range record.Context {
+				context[k] = v
+			}
+			record.Context = context
+		}
+
+		if record.ID == "" {
+			record.ID = recordID(record)
+		}
+
+		s.records[record.ID] = record
+	}
+
+	writeJSON(w, http.StatusOK, hostdb.PostRecordsResponse{OK: true})
+
+}
+
+func (s *Server) putRecord(w http.ResponseWriter, r *http.Request, id string) {
+
+	var record hostdb.Record
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	record.ID = id
+
+	s.mutex.Lock()
+	s.records[id] = record
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, hostdb.PutRecordResponse{ID: id, OK: true})
+
+}
+
+func (s *Server) patchRecord(w http.ResponseWriter, r *http.Request, id string) {
+
+	var patch hostdb.RecordPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, found := s.records[id]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("record %s not found", id))
+		return
+	}
+
+	record, err := patch.Apply(record)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.records[id] = record
+
+	writeJSON(w, http.StatusOK, hostdb.PatchRecordResponse{ID: id, OK: true})
+
+}
+
+func (s *Server) deleteRecord(w http.ResponseWriter, id string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, found := s.records[id]; !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("record %s not found", id))
+		return
+	}
+
+	delete(s.records, id)
+
+	writeJSON(w, http.StatusOK, hostdb.DeleteRecordResponse{ID: id, OK: true})
+
+}
+
+func (s *Server) deleteRecords(w http.ResponseWriter, r *http.Request) {
+
+	// paging parameters are ignored by matches, so they don't count as a filter
+	query := r.URL.Query()
+	query.Del(hostdb.QueryParamLimit)
+	query.Del(hostdb.QueryParamOffset)
+	if len(query) < 1 {
+		writeJSON(w, http.StatusBadRequest, hostdb.DeleteRecordsResponse{Error: "a query is required"})
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records := s.sortedRecords(query)
+	for _, record := range records {
+		delete(s.records, record.ID)
+	}
+
+	writeJSON(w, http.StatusOK, hostdb.DeleteRecordsResponse{Count: len(records), OK: true})
+
+}
+
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+
+	start := time.Now()
+	field := strings.TrimPrefix(r.URL.Path, "/catalog/")
+
+	if r.Method != http.MethodGet || field == "" {
+		writeError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	quantities := map[string]int{}
+
+	s.mutex.Lock()
+	for _, record := range s.records {
+		if value, found := fieldValue(record, field); found {
+			quantities[value]++
+		}
+	}
+	s.mutex.Unlock()
+
+	if count, _ := strconv.ParseBool(r.URL.Query().Get("count")); count {
+		writeJSON(w, http.StatusOK, hostdb.GetCatalogQuantityResponse{
+			Count:     len(quantities),
+			QueryTime: time.Since(start).String(),
+			Catalog:   quantities,
+		})
+		return
+	}
+
+	catalog := make([]string, 0, len(quantities))
+	for value := range quantities {
+		catalog = append(catalog, value)
+	}
+	sort.Strings(catalog)
+
+	writeJSON(w, http.StatusOK, hostdb.GetCatalogResponse{
+		Count:     len(catalog),
+		QueryTime: time.Since(start).String(),
+		Catalog:   catalog,
+	})
+
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+
+	response := hostdb.GetStatsResponse{
+		Hostname:           "hostdbtest",
+		LastSeenCollectors: map[string]string{},
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	response.TotalRecords = len(s.records)
+
+	var newest, oldest hostdb.Timestamp
+	lastSeen := map[string]hostdb.Timestamp{}
+
+	for _, record := range s.records {
+		if record.Timestamp.IsZero() {
+			continue
+		}
+
+		if newest.IsZero() || record.Timestamp.After(newest.Time) {
+			newest = record.Timestamp
+		}
+
+		if oldest.IsZero() || record.Timestamp.Before(oldest.Time) {
+			oldest = record.Timestamp
+		}
+
+		if record.Committer != "" && record.Timestamp.After(lastSeen[record.Committer].Time) {
+			lastSeen[record.Committer] = record.Timestamp
+		}
+	}
+
+	response.NewestRecord = newest.String()
+	response.OldestRecord = oldest.String()
+
+	for committer, timestamp := range lastSeen {
+		response.LastSeenCollectors[committer] = timestamp.String()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, hostdb.GetHealthResponse{App: "ok", DB: "ok"})
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Version)
+}
+
+// sortedRecords returns the records matching the query, sorted by ID; the mutex must be held
+func (s *Server) sortedRecords(query url.Values) []hostdb.Record {
+
+	records := make([]hostdb.Record, 0, len(s.records))
+
+	for _, record := range s.records {
+		if matches(record, query) {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	return records
+
+}
+
+// matches reports whether the record has every value in the query, ignoring paging parameters;
+// an ip matches the primary IP, or any of the record's addresses
+func matches(record hostdb.Record, query url.Values) bool {
+
+	for key := range query {
+		if key == hostdb.QueryParamLimit || key == hostdb.QueryParamOffset {
+			continue
+		}
+
+		if key == "ip" {
+			if !hasAddress(record, query.Get(key)) {
+				return false
+			}
+			continue
+		}
+
+		value, found := fieldValue(record, key)
+		if !found || value != query.Get(key) {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+func hasAddress(record hostdb.Record, ip string) bool {
+
+	for _, address := range record.AllAddresses() {
+		if address.IP == ip {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// fieldValue returns a top-level field of the record, or else a value from its context or data
+func fieldValue(record hostdb.Record, field string) (string, bool) {
+
+	switch field {
+	case "id":
+		return record.ID, record.ID != ""
+	case "type":
+		return record.Type, record.Type != ""
+	case "hostname":
+		return record.Hostname, record.Hostname != ""
+	case "ip":
+		return record.IP, record.IP != ""
+	case "committer":
+		return record.Committer, record.Committer != ""
+	}
+
+	if value, found := record.Context[field]; found {
+		return fmt.Sprint(value), true
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(record.Data, &data); err == nil {
+		if value, found := data[field]; found {
+			return fmt.Sprint(value), true
+		}
+	}
+
+	return "", false
+
+}
+
+// containsContext reports whether every value in want is also in have
+func containsContext(have map[string]interface{}, want map[string]interface{}) bool {
+
+	for k, v := range want {
+		if fmt.Sprint(have[k]) != fmt.Sprint(v) {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+// paging returns the limit and offset query parameters
+func paging(query url.Values) (limit int, offset int, err error) {
+
+	if v := query.Get(hostdb.QueryParamLimit); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %s", hostdb.QueryParamLimit, v)
+		}
+	}
+
+	if v := query.Get(hostdb.QueryParamOffset); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %s", hostdb.QueryParamOffset, v)
+		}
+	}
+
+	return limit, offset, nil
+
+}
+
+// recordID returns an ID for a record which doesn't have one
+func recordID(record hostdb.Record) string {
+
+	sum := sha1.Sum([]byte(strings.Join([]string{record.Type, record.Hostname, record.IP}, "\x00")))
+
+	return hex.EncodeToString(sum[:])
+
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, hostdb.GenericError{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// there's nobody to tell, if the response can't be written
+	_ = json.NewEncoder(w).Encode(v)
+
+}
@@ -0,0 +1,354 @@
+package hostdbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pdxfixit/hostdb"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRecordSet = hostdb.RecordSet{
+	Type:      "test",
+	Timestamp: hostdb.MustParseTimestamp("2003-04-05 06:07:08"),
+	Context:   map[string]interface{}{"region": "pdx"},
+	Committer: "hostdbtest",
+	Records: []hostdb.Record{
+		{ID: "a", Hostname: "alpha", IP: "10.0.0.1", Data: json.RawMessage(`{"flavor":"small"}`)},
+		{ID: "b", Hostname: "bravo", IP: "10.0.0.2", Data: json.RawMessage(`{"flavor":"large"}`)},
+		{ID: "c", Hostname: "charlie", IP: "10.0.0.3", Data: json.RawMessage(`{"flavor":"small"}`)},
+	},
+}
+
+func newTestClient(t *testing.T) (*Server, *hostdb.Client) {
+
+	server := NewServer()
+
+	client, err := server.Client(hostdb.ClientConfig{Gzip: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return server, client
+
+}
+
+func TestServer_Records(t *testing.T) {
+
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	if err := client.SendRecordSet(testRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	records := server.Records()
+	if !assert.Len(t, records, 3, "stored records") {
+		return
+	}
+
+	assert.Equal(t, "test", records[0].Type, "type from the record set")
+	assert.Equal(t, "2003-04-05 06:07:08", records[0].Timestamp.String(), "timestamp from the record set")
+	assert.Equal(t, "pdx", records[0].Context["region"], "context from the record set")
+
+	// query
+	response, err := client.GetRecords(hostdb.RecordQuery{Type: "test", Params: map[string]string{"flavor": "small"}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 2, response.Count, "matching records")
+	assert.Contains(t, response.Records, "a", "first small record")
+	assert.Contains(t, response.Records, "c", "second small record")
+
+	// paging
+	var ids []string
+	it := client.Records(context.Background(), hostdb.RecordQuery{Type: "test", Limit: 2})
+	for it.Next() {
+		ids = append(ids, it.Record().ID)
+	}
+
+	assert.NoError(t, it.Err(), "iterator")
+	assert.Equal(t, []string{"a", "b", "c"}, ids, "every page")
+
+	// put, patch and get
+	if err := client.SendRecord(hostdb.Record{ID: "d", Type: "test", Hostname: "delta"}, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := client.PatchRecord("d", hostdb.RecordPatch{Context: map[string]interface{}{"region": "sea"}}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	record, err := client.GetRecord("d")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "delta", record.Hostname, "put record")
+	assert.Equal(t, "sea", record.Context["region"], "patched record")
+
+	// posting the record set again replaces the records with the same type and context
+	if err := client.SendRecordSet(hostdb.RecordSet{Type: "test", Context: testRecordSet.Context, Records: testRecordSet.Records[:1]}, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, server.Records(), 2, "replaced records, and the record from another context")
+
+	// delete
+	if _, err := client.DeleteRecord("a"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	deleted, err := client.DeleteRecords(hostdb.RecordQuery{Params: map[string]string{"region": "sea"}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 1, deleted.Count, "deleted by query")
+	assert.Empty(t, server.Records(), "all deleted")
+
+	_, err = client.GetRecord("a")
+	var errorResponse hostdb.ErrorResponse
+	assert.True(t, errors.As(err, &errorResponse), "error response")
+	assert.Equal(t, http.StatusNotFound, errorResponse.Code, "not found")
+
+}
+
+func TestServer_Endpoints(t *testing.T) {
+
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	server.AddRecords(testRecordSet.Records...)
+	server.AddRecords(hostdb.Record{ID: "d", Type: "other", Committer: "collector", Timestamp: hostdb.MustParseTimestamp("2004-01-01 00:00:00")})
+
+	catalog, err := client.Catalog("flavor")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, []string{"large", "small"}, catalog.Catalog, "catalog")
+
+	quantity, err := client.CatalogQuantity("flavor")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, map[string]int{"large": 1, "small": 2}, quantity.Catalog, "catalog quantity")
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 4, stats.TotalRecords, "total records")
+	assert.Equal(t, "2004-01-01 00:00:00", stats.LastSeenCollectors["collector"], "last seen collector")
+
+	health, err := client.Health()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "ok", health.App, "health")
+
+	version, err := client.Version()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "v0", version.App.APIVersion, "version")
+
+}
+
+func TestServer_Requests(t *testing.T) {
+
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	if err := client.SendRecordSet(testRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	requests := server.Requests()
+	if !assert.Len(t, requests, 1, "requests") {
+		return
+	}
+
+	assert.Equal(t, http.MethodPost, requests[0].Method, "method")
+	assert.Equal(t, "/records/", requests[0].Path, "path")
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"), "content type")
+
+	var rs hostdb.RecordSet
+	if err := json.Unmarshal(requests[0].Body, &rs); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, testRecordSet, rs, "decompressed body")
+
+	server.Reset()
+	assert.Empty(t, server.Requests(), "reset requests")
+	assert.Empty(t, server.Records(), "reset records")
+
+}
+
+func TestServer_InjectFailure(t *testing.T) {
+
+	server := NewServer()
+	defer server.Close()
+
+	client, err := server.Client(hostdb.ClientConfig{
+		Retry: hostdb.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a single failure is retried
+	server.InjectFailure(Failure{Method: http.MethodPost, Status: http.StatusBadGateway, Count: 1})
+
+	if err := client.SendRecordSet(testRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, server.Requests(), 2, "failed, then retried")
+
+	// a permanent failure is returned
+	server.InjectFailure(Failure{Path: "/records/", Status: http.StatusConflict, Message: "conflicting record"})
+
+	err = client.SendRecordSet(testRecordSet, "test")
+
+	var errorResponse hostdb.ErrorResponse
+	if assert.True(t, errors.As(err, &errorResponse), "error response") {
+		assert.Equal(t, http.StatusConflict, errorResponse.Code, "status")
+		assert.Equal(t, "conflicting record", errorResponse.Message, "message")
+	}
+
+	_, err = client.Health()
+	assert.NoError(t, err, "other paths are unaffected")
+
+}
+
+func TestServer_SetLatency(t *testing.T) {
+
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	server.SetLatency(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.HealthContext(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "deadline exceeded")
+
+}
+
+func TestServer_Records_Addresses(t *testing.T) {
+
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	server.AddRecords(
+		hostdb.Record{ID: "a", Type: "test", IP: "10.0.0.1", Addresses: []hostdb.Address{hostdb.NewAddress("2001:db8::1", "eth0", "")}},
+		hostdb.Record{ID: "b", Type: "test", IP: "10.0.0.2"},
+	)
+
+	for ip, expected := range map[string]string{"10.0.0.1": "a", "2001:db8::1": "a", "10.0.0.2": "b"} {
+		response, err := client.GetRecords(hostdb.RecordQuery{IP: ip})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		assert.Equal(t, 1, response.Count, ip)
+		assert.Contains(t, response.Records, expected, ip)
+	}
+
+}
+
+func TestServer_Records_Batches(t *testing.T) {
+
+	server := NewServer()
+	defer server.Close()
+
+	client, err := server.Client(hostdb.ClientConfig{Batch: hostdb.BatchConfig{MaxRecords: 2, Append: true}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rs := hostdb.RecordSet{Type: "test", Context: map[string]interface{}{"region": "pdx"}}
+	for i := 0; i < 5; i++ {
+		rs.Records = append(rs.Records, hostdb.Record{ID: fmt.Sprintf("%d", i), Hostname: fmt.Sprintf("host-%d", i)})
+	}
+
+	if err := client.SendRecordSet(rs, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	records := server.Records()
+	if assert.Len(t, records, 5, "every batch is stored") {
+		assert.Equal(t, "pdx", records[4].Context["region"], "context of the set")
+	}
+
+	// sending a smaller set still replaces the records which are no longer present
+	rs.Records = rs.Records[:3]
+	if err := client.SendRecordSet(rs, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, server.Records(), 3, "stale records are removed")
+
+}
+
+func TestServer_DeleteRecords_Paging(t *testing.T) {
+
+	server := NewServer()
+	defer server.Close()
+
+	server.AddRecords(testRecordSet.Records...)
+
+	request, err := http.NewRequest(http.MethodDelete, server.URL+"/records/?_limit=1&_offset=0", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_ = response.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "paging parameters are not a query")
+	assert.Len(t, server.Records(), 3, "nothing deleted")
+
+}
+
+func TestServer_Records_EscapedID(t *testing.T) {
+
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	id := "rack/1?slot=2#a"
+	if err := client.SendRecord(hostdb.Record{ID: id, Type: "test", Hostname: "alpha"}, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	record, err := client.GetRecord(id)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "alpha", record.Hostname, "the record which was put")
+
+	if _, err := client.DeleteRecord(id); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Empty(t, server.Records(), "the record which was put is deleted")
+
+}
@@ -0,0 +1,182 @@
+package hostdb
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMissingID is returned when a record without an ID is sent, fetched, patched or deleted
+var ErrMissingID = errors.New("a record ID is required")
+
+// DefaultNaturalKey is used to identify records of types without a configured natural key
+const DefaultNaturalKey = "hostname"
+
+// namespaceURL is the RFC 4122 namespace for URLs
+var namespaceURL = uuid{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// idNamespace is the namespace of every record ID generated by IDGenerator
+var idNamespace = uuidV5(namespaceURL, "https://github.com/pdxfixit/hostdb")
+
+// IDGenerator derives name-based (version 5) UUIDs for records, so every collector
+// produces the same ID for the same host
+type IDGenerator struct {
+
+	// the context fields required for each type, as in APIv0Config.ContextFields
+	ContextFields map[string][]string
+
+	// the natural key of each type; hostname, ip, or a path into the data such as .serial
+	// optional; defaults to DefaultNaturalKey
+	NaturalKeys map[string]string
+}
+
+// NewIDGenerator will return an IDGenerator, using the context fields of the API configuration
+func NewIDGenerator(config APIv0Config) IDGenerator {
+	return IDGenerator{ContextFields: config.ContextFields, NaturalKeys: map[string]string{}}
+}
+
+// ID will return the ID of the record, derived from its Type, required context fields and natural key
+func (g IDGenerator) ID(r Record) (string, error) {
+
+	if r.Type == "" {
+		return "", errors.New("a record type is required, to generate an ID")
+	}
+
+	name := []string{r.Type}
+
+	for _, field := range g.ContextFields[r.Type] {
+		value, found := r.Context[field]
+		if !found || value == nil {
+			return "", fmt.Errorf("the %s context field is required for %s records, to generate an ID", field, r.Type)
+		}
+
+		valueBytes, err := canonicalJSON(value)
+		if err != nil {
+			return "", err
+		}
+
+		name = append(name, fmt.Sprintf("%s=%s", field, valueBytes))
+	}
+
+	key := g.NaturalKeys[r.Type]
+	if key == "" {
+		key = DefaultNaturalKey
+	}
+
+	value, err := naturalKey(r, key)
+	if err != nil {
+		return "", err
+	}
+
+	name = append(name, fmt.Sprintf("%s=%s", key, value))
+
+	return uuidV5(idNamespace, strings.Join(name, "\n")).String(), nil
+
+}
+
+// AssignIDs returns a copy of the record set, with an ID generated for every record which doesn't have one.
+// Records inherit the Type and Context of the set, as they will on the server.
+func (g IDGenerator) AssignIDs(rs RecordSet) (RecordSet, error) {
+
+	records := make([]Record, len(rs.Records))
+
+	for i, r := range rs.Records {
+		if r.ID == "" {
+			id, err := g.ID(rs.inherit(r))
+			if err != nil {
+				return rs, fmt.Errorf("record %d: %v", i, err)
+			}
+			r.ID = id
+		}
+
+		records[i] = r
+	}
+
+	rs.Records = records
+
+	return rs, nil
+
+}
+
+// naturalKey returns the value of the key, which is a top-level field, or a path into the data
+func naturalKey(r Record, key string) (string, error) {
+
+	switch key {
+	case "hostname":
+		if r.Hostname == "" {
+			return "", errors.New("a hostname is required, to generate an ID")
+		}
+		return strings.ToLower(r.Hostname), nil
+	case "ip":
+		if r.IP == "" {
+			return "", errors.New("an IP is required, to generate an ID")
+		}
+		return r.IP, nil
+	}
+
+	var data interface{}
+	if len(r.Data) > 0 {
+		if err := unmarshalNumbers(r.Data, &data); err != nil {
+			return "", err
+		}
+	}
+
+	for _, segment := range strings.Split(strings.TrimPrefix(key, "."), ".") {
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			data = nil
+			break
+		}
+		data = object[segment]
+	}
+
+	if data == nil {
+		return "", fmt.Errorf("the data path %s is required, to generate an ID", key)
+	}
+
+	valueBytes, err := canonicalJSON(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(valueBytes), nil
+
+}
+
+// uuid is an RFC 4122 UUID
+type uuid [16]byte
+
+func (u uuid) String() string {
+
+	var buffer [36]byte
+
+	hex.Encode(buffer[0:8], u[0:4])
+	buffer[8] = '-'
+	hex.Encode(buffer[9:13], u[4:6])
+	buffer[13] = '-'
+	hex.Encode(buffer[14:18], u[6:8])
+	buffer[18] = '-'
+	hex.Encode(buffer[19:23], u[8:10])
+	buffer[23] = '-'
+	hex.Encode(buffer[24:], u[10:])
+
+	return string(buffer[:])
+
+}
+
+// uuidV5 returns the name-based UUID of the name, using SHA-1
+func uuidV5(namespace uuid, name string) (u uuid) {
+
+	hash := sha1.New()
+	hash.Write(namespace[:])
+	hash.Write([]byte(name))
+
+	copy(u[:], hash.Sum(nil))
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return u
+
+}
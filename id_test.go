@@ -0,0 +1,112 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUUIDv5(t *testing.T) {
+
+	assert.Equal(t, "4c565f0d-3f5a-5890-b41b-20cf47701c5e", uuidV5(namespaceURL, "http://python.org/").String(), "RFC 4122 name-based UUID")
+	assert.Equal(t, "1bc3bd2d-7ab7-507b-9b8e-06f2d8a318a8", idNamespace.String(), "record ID namespace")
+
+}
+
+func TestIDGenerator_ID(t *testing.T) {
+
+	generator := NewIDGenerator(APIv0Config{
+		ContextFields: map[string][]string{"openstack": {"region", "tenant"}},
+	})
+	generator.NaturalKeys["oneview"] = ".serial.number"
+
+	record := Record{
+		Type:     "openstack",
+		Hostname: "Alpha",
+		Context:  map[string]interface{}{"region": "pdx", "tenant": "ops", "other": true},
+	}
+
+	id, err := generator.ID(record)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "e76e7db1-88d4-5b5a-8e80-6d57a8bf92bc", id, "derived from type, context fields and hostname")
+
+	// other fields don't matter
+	record.IP = "10.0.0.1"
+	record.Context["other"] = false
+
+	again, err := generator.ID(record)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, id, again, "deterministic")
+
+	// required context fields
+	delete(record.Context, "tenant")
+	_, err = generator.ID(record)
+	assert.EqualError(t, err, "the tenant context field is required for openstack records, to generate an ID", "missing context field")
+
+	// data paths
+	id, err = generator.ID(Record{Type: "oneview", Data: json.RawMessage(`{"serial":{"number":"ABC123"}}`)})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, id, 36, "derived from a data path")
+
+	_, err = generator.ID(Record{Type: "oneview", Data: json.RawMessage(`{"serial":"ABC123"}`)})
+	assert.Error(t, err, "missing data path")
+
+	_, err = generator.ID(Record{Type: "test"})
+	assert.Error(t, err, "missing hostname")
+
+	_, err = generator.ID(Record{Hostname: "alpha"})
+	assert.Error(t, err, "missing type")
+
+}
+
+func TestIDGenerator_AssignIDs(t *testing.T) {
+
+	generator := NewIDGenerator(APIv0Config{
+		ContextFields: map[string][]string{"openstack": {"region", "tenant"}},
+	})
+
+	rs := RecordSet{
+		Type:    "openstack",
+		Context: map[string]interface{}{"region": "pdx"},
+		Records: []Record{
+			{Hostname: "alpha", Context: map[string]interface{}{"tenant": "ops"}},
+			{ID: "existing", Hostname: "bravo"},
+		},
+	}
+
+	assigned, err := generator.AssignIDs(rs)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "e76e7db1-88d4-5b5a-8e80-6d57a8bf92bc", assigned.Records[0].ID, "inherits the type and context of the set")
+	assert.Equal(t, "existing", assigned.Records[1].ID, "existing IDs are kept")
+	assert.Equal(t, "", rs.Records[0].ID, "the caller's records are untouched")
+
+	// the second record has no tenant
+	rs.Records[1].ID = ""
+	_, err = generator.AssignIDs(rs)
+	assert.Error(t, err, "missing context field")
+
+}
+
+func TestClient_SendRecord_MissingID(t *testing.T) {
+
+	client, err := NewClient(ClientConfig{DryRun: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, ErrMissingID, client.SendRecord(Record{Type: "test"}, "test"), "empty ID")
+
+}
@@ -0,0 +1,125 @@
+package hostdb
+
+import (
+	"context"
+	"sort"
+)
+
+// RecordIterator walks through every page of records matching a query.
+//
+//	it := client.Records(ctx, hostdb.RecordQuery{Type: "openstack"})
+//	for it.Next() {
+//		fmt.Println(it.Record().Hostname)
+//	}
+//	if err := it.Err(); err != nil {
+//		log.Println(err)
+//	}
+type RecordIterator struct {
+	ctx    context.Context
+	client *Client
+	query  RecordQuery
+
+	page   []Record
+	record Record
+	count  int
+	seen   int
+	done   bool
+	err    error
+}
+
+// Records will return an iterator over all records matching the query.
+// Pages are requested using the query's Limit (or the server's default limit),
+// starting at the query's Offset, until the total Count has been reached.
+func (c *Client) Records(ctx context.Context, query RecordQuery) *RecordIterator {
+	return &RecordIterator{
+		ctx:    ctx,
+		client: c,
+		query:  query,
+	}
+}
+
+// Next will advance to the next record, fetching the next page when required.
+// It returns false when there are no more records, or an error has occurred.
+func (it *RecordIterator) Next() bool {
+
+	if it.done || it.err != nil {
+		return false
+	}
+
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	if len(it.page) < 1 {
+		if !it.fetch() {
+			return false
+		}
+	}
+
+	it.record = it.page[0]
+	it.page = it.page[1:]
+	it.seen++
+
+	return true
+
+}
+
+// Record returns the current record
+func (it *RecordIterator) Record() Record {
+	return it.record
+}
+
+// Count returns the total number of matching records, as reported by the most recent page
+func (it *RecordIterator) Count() int {
+	return it.count
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+// fetch will retrieve the next page of records, reporting whether any were found
+func (it *RecordIterator) fetch() bool {
+
+	// once the reported count has been reached, there's nothing left to request
+	if it.seen > 0 && it.count > 0 && it.seen >= it.count {
+		it.done = true
+		return false
+	}
+
+	response, err := it.client.GetRecordsContext(it.ctx, it.query)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.count = response.Count
+
+	if len(response.Records) < 1 {
+		it.done = true
+		return false
+	}
+
+	// records are keyed by ID; sort them, so each page is walked in a stable order
+	ids := make([]string, 0, len(response.Records))
+	for id := range response.Records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	it.page = make([]Record, 0, len(ids))
+	for _, id := range ids {
+		record := response.Records[id]
+		if record.ID == "" {
+			record.ID = id
+		}
+		it.page = append(it.page, record)
+	}
+
+	it.query.Offset += len(it.page)
+
+	return true
+
+}
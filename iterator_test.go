@@ -0,0 +1,95 @@
+package hostdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPagingServer returns a fake HostDB, which serves the given number of records in pages
+func newPagingServer(t *testing.T, total int, requests *int) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get(QueryParamLimit))
+		offset, _ := strconv.Atoi(r.URL.Query().Get(QueryParamOffset))
+
+		response := GetRecordsResponse{Count: total, Records: map[string]Record{}}
+		for i := offset; i < offset+limit && i < total; i++ {
+			id := fmt.Sprintf("record-%03d", i)
+			response.Records[id] = Record{ID: id, Type: "test"}
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+
+}
+
+func TestRecordIterator(t *testing.T) {
+
+	var requests int
+	testServer := newPagingServer(t, 7, &requests)
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var ids []string
+	it := client.Records(context.Background(), RecordQuery{Type: "test", Limit: 3})
+	for it.Next() {
+		ids = append(ids, it.Record().ID)
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, ids, 7, "all records")
+	assert.Equal(t, "record-000", ids[0], "first record")
+	assert.Equal(t, "record-006", ids[6], "last record")
+	assert.Equal(t, 7, it.Count(), "count")
+	assert.Equal(t, 3, requests, "stops once the count has been reached")
+	assert.False(t, it.Next(), "exhausted iterator")
+
+}
+
+func TestRecordIterator_Canceled(t *testing.T) {
+
+	var requests int
+	testServer := newPagingServer(t, 10, &requests)
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var seen int
+	it := client.Records(ctx, RecordQuery{Limit: 2})
+	for it.Next() {
+		seen++
+		if seen == 3 {
+			cancel()
+		}
+	}
+
+	assert.True(t, errors.Is(it.Err(), context.Canceled), "canceled")
+	assert.Equal(t, 3, seen, "records seen before canceling")
+	assert.Equal(t, 2, requests, "no further pages requested")
+
+}
@@ -0,0 +1,140 @@
+package hostdb
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fields are key/value pairs which describe a log entry (e.g. type, records, duration)
+type Fields map[string]interface{}
+
+// Logger receives structured log entries; implementations can adapt any logging library
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+// NopLogger discards all log entries, and is the default
+type NopLogger struct{}
+
+// Debug does nothing
+func (NopLogger) Debug(msg string, fields Fields) {}
+
+// Info does nothing
+func (NopLogger) Info(msg string, fields Fields) {}
+
+// Warn does nothing
+func (NopLogger) Warn(msg string, fields Fields) {}
+
+// Error does nothing
+func (NopLogger) Error(msg string, fields Fields) {}
+
+// StdLogger adapts a standard library logger, writing each entry on a single line:
+//
+//	INFO sent record set records=3 type=openstack
+type StdLogger struct {
+	// defaults to the standard logger, from log.Default
+	Logger *log.Logger
+
+	// debug entries are discarded, unless Verbose is set
+	Verbose bool
+}
+
+// NewStdLogger will return a Logger which writes to l, or the standard logger when l is nil
+func NewStdLogger(l *log.Logger) Logger {
+	return StdLogger{Logger: l}
+}
+
+var (
+	packageLogger      Logger = NopLogger{}
+	packageLoggerMutex sync.RWMutex
+)
+
+// SetLogger will set the Logger used by RecordSet.Save, and by any client without its own Logger
+func SetLogger(l Logger) {
+
+	packageLoggerMutex.Lock()
+	defer packageLoggerMutex.Unlock()
+
+	if l == nil {
+		l = NopLogger{}
+	}
+
+	packageLogger = l
+
+}
+
+// getLogger returns the Logger set by SetLogger
+func getLogger() Logger {
+
+	packageLoggerMutex.RLock()
+	defer packageLoggerMutex.RUnlock()
+
+	return packageLogger
+
+}
+
+// logger returns the client's Logger, or the package Logger if it has none
+func (c *Client) logger() Logger {
+
+	if c.config.Logger != nil {
+		return c.config.Logger
+	}
+
+	return getLogger()
+
+}
+
+// Debug will write a debug entry, if Verbose is set
+func (l StdLogger) Debug(msg string, fields Fields) {
+	if l.Verbose {
+		l.output("DEBUG", msg, fields)
+	}
+}
+
+// Info will write an info entry
+func (l StdLogger) Info(msg string, fields Fields) {
+	l.output("INFO", msg, fields)
+}
+
+// Warn will write a warning entry
+func (l StdLogger) Warn(msg string, fields Fields) {
+	l.output("WARN", msg, fields)
+}
+
+// Error will write an error entry
+func (l StdLogger) Error(msg string, fields Fields) {
+	l.output("ERROR", msg, fields)
+}
+
+// output will write the entry, with its fields sorted by key
+func (l StdLogger) output(level string, msg string, fields Fields) {
+
+	logger := l.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	var builder strings.Builder
+	builder.WriteString(level)
+	builder.WriteString(" ")
+	builder.WriteString(msg)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(&builder, " %s=%v", k, fields[k])
+	}
+
+	_ = logger.Output(3, builder.String())
+
+}
@@ -0,0 +1,106 @@
+package hostdb
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogger records every entry, for assertions
+type testLogger struct {
+	mutex   sync.Mutex
+	entries []testLogEntry
+}
+
+type testLogEntry struct {
+	level  string
+	msg    string
+	fields Fields
+}
+
+func (l *testLogger) log(level string, msg string, fields Fields) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, testLogEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *testLogger) Debug(msg string, fields Fields) { l.log("DEBUG", msg, fields) }
+func (l *testLogger) Info(msg string, fields Fields)  { l.log("INFO", msg, fields) }
+func (l *testLogger) Warn(msg string, fields Fields)  { l.log("WARN", msg, fields) }
+func (l *testLogger) Error(msg string, fields Fields) { l.log("ERROR", msg, fields) }
+
+func TestStdLogger(t *testing.T) {
+
+	var buffer bytes.Buffer
+	logger := StdLogger{Logger: log.New(&buffer, "", 0)}
+
+	logger.Info("sent record set", Fields{"type": "test", "records": 3})
+	logger.Debug("hidden", nil)
+
+	assert.Equal(t, "INFO sent record set records=3 type=test\n", buffer.String(), "info entry")
+
+	buffer.Reset()
+	logger.Verbose = true
+	logger.Debug("shown", nil)
+	logger.Error("failed", Fields{"status": 500})
+
+	assert.Equal(t, "DEBUG shown\nERROR failed status=500\n", buffer.String(), "debug and error entries")
+
+}
+
+func TestClient_Logger(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	logger := &testLogger{}
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Logger: logger})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "logger-test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !assert.Len(t, logger.entries, 3, "log entries") {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "INFO", logger.entries[0].level, "level")
+	assert.Equal(t, "test", logger.entries[0].fields["type"], "type field")
+	assert.Equal(t, 1, logger.entries[0].fields["records"], "records field")
+
+	assert.Equal(t, "DEBUG", logger.entries[1].level, "request entry")
+	assert.Equal(t, http.StatusOK, logger.entries[1].fields["status"], "status field")
+	assert.Equal(t, "logger-test", logger.entries[1].fields["unique_identifier"], "unique identifier field")
+
+	assert.Contains(t, logger.entries[2].fields, "duration", "duration field")
+
+	// the package logger is used by clients without their own
+	packageLogger := &testLogger{}
+	SetLogger(packageLogger)
+	defer SetLogger(nil)
+
+	client, err = NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecord(TestRecord, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.NotEmpty(t, packageLogger.entries, "package logger entries")
+
+}
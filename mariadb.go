@@ -3,7 +3,6 @@ package hostdb
 import (
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 )
 
@@ -57,7 +56,7 @@ func (c MariadbWhereClauses) Stringify() (whereSQL string, values []interface{},
 	var whereBuilder strings.Builder
 
 	if _, err := fmt.Fprintf(&whereBuilder, "WHERE "); err != nil {
-		log.Println(err.Error())
+		getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 	}
 
 	for groupCounter, group := range c.Groups {
@@ -68,13 +67,13 @@ func (c MariadbWhereClauses) Stringify() (whereSQL string, values []interface{},
 			}
 
 			if _, err := fmt.Fprintf(&whereBuilder, "%s ", c.Relativity); err != nil {
-				log.Println(err.Error())
+				getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 			}
 		}
 
 		if len(group.Clauses) > 1 {
 			if _, err := fmt.Fprintf(&whereBuilder, "( "); err != nil {
-				log.Println(err.Error())
+				getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 			}
 		}
 
@@ -101,7 +100,7 @@ func (c MariadbWhereClauses) Stringify() (whereSQL string, values []interface{},
 			// if there are existing arguments, use the relativity (AND/OR)
 			if whereBuilder.Len() > 8 && clauseCounter >= 1 {
 				if _, err := fmt.Fprintf(&whereBuilder, "%v ", clause.Relativity); err != nil {
-					log.Println(err.Error())
+					getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 				}
 			}
 
@@ -110,7 +109,7 @@ func (c MariadbWhereClauses) Stringify() (whereSQL string, values []interface{},
 			// e.g. WHERE (a = 1 OR b = 1) AND (a = 2 OR b = 2)
 			if len(clause.Key) > 1 {
 				if _, err := fmt.Fprintf(&whereBuilder, "( "); err != nil {
-					log.Println(err.Error())
+					getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 				}
 			}
 
@@ -120,36 +119,36 @@ func (c MariadbWhereClauses) Stringify() (whereSQL string, values []interface{},
 				// if this is not the first key, make sure we use OR
 				if counter > 1 {
 					if _, err := fmt.Fprintf(&whereBuilder, "%v ", "OR"); err != nil {
-						log.Println(err.Error())
+						getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 					}
 				}
 
 				if _, err := fmt.Fprintf(&whereBuilder, "%v %v ", key, clause.Operator); err != nil {
-					log.Println(err.Error())
+					getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 				}
 
 				// if there are multiple values, wrap them in parentheses
 				if len(clause.Value) > 1 {
 					if _, err := fmt.Fprintf(&whereBuilder, "("); err != nil {
-						log.Println(err.Error())
+						getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 					}
 					for i := 0; i < len(clause.Value); i++ {
 						if _, err := fmt.Fprintf(&whereBuilder, "?"); err != nil {
-							log.Println(err.Error())
+							getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 						}
 						values = append(values, clause.Value[i])
 						if (i + 1) < len(clause.Value) {
 							if _, err := fmt.Fprintf(&whereBuilder, ","); err != nil {
-								log.Println(err.Error())
+								getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 							}
 						}
 					}
 					if _, err := fmt.Fprintf(&whereBuilder, ") "); err != nil {
-						log.Println(err.Error())
+						getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 					}
 				} else if len(clause.Value) == 1 {
 					if _, err := fmt.Fprintf(&whereBuilder, "? "); err != nil {
-						log.Println(err.Error())
+						getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 					}
 					values = append(values, clause.Value[0])
 				}
@@ -159,14 +158,14 @@ func (c MariadbWhereClauses) Stringify() (whereSQL string, values []interface{},
 
 			if len(clause.Key) > 1 {
 				if _, err := fmt.Fprintf(&whereBuilder, ")"); err != nil {
-					log.Println(err.Error())
+					getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 				}
 			}
 		}
 
 		if len(group.Clauses) > 1 {
 			if _, err := fmt.Fprintf(&whereBuilder, ") "); err != nil {
-				log.Println(err.Error())
+				getLogger().Error("failed to build WHERE clause", Fields{"error": err})
 			}
 		}
 	}
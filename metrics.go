@@ -0,0 +1,282 @@
+package hostdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsName is the expvar name of the metrics used by clients without their own
+const DefaultMetricsName = "hostdb"
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency histogram
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics receives measurements of client operations
+type Metrics interface {
+	// ObserveRequest is called after every attempt at a request;
+	// status is zero when no response was received
+	ObserveRequest(method string, status int, duration time.Duration, bytesSent int64)
+
+	// AddRecords is called with the number of records successfully sent, by record type
+	AddRecords(recordType string, count int)
+}
+
+// ExpvarMetrics implements Metrics using expvar, so measurements are visible at /debug/vars,
+// and can be written in the Prometheus text format
+type ExpvarMetrics struct {
+	vars      *expvar.Map
+	requests  *expvar.Map // by "method status"
+	latency   *histograms // by method
+	bytesSent *expvar.Int
+	records   *expvar.Map // by type
+}
+
+var (
+	defaultMetrics     *ExpvarMetrics
+	defaultMetricsOnce sync.Once
+)
+
+// NewExpvarMetrics will return new metrics, published with expvar under name,
+// unless name is empty, or already in use
+func NewExpvarMetrics(name string) (*ExpvarMetrics, error) {
+
+	if name != "" && expvar.Get(name) != nil {
+		return nil, fmt.Errorf("expvar %s is already published", name)
+	}
+
+	m := &ExpvarMetrics{
+		vars:      new(expvar.Map).Init(),
+		requests:  new(expvar.Map).Init(),
+		latency:   &histograms{byKey: map[string]*histogram{}},
+		bytesSent: new(expvar.Int),
+		records:   new(expvar.Map).Init(),
+	}
+
+	m.vars.Set("requests", m.requests)
+	m.vars.Set("request_duration_seconds", m.latency)
+	m.vars.Set("sent_bytes", m.bytesSent)
+	m.vars.Set("sent_records", m.records)
+
+	if name != "" {
+		expvar.Publish(name, m.vars)
+	}
+
+	return m, nil
+
+}
+
+// DefaultMetrics returns the metrics used by clients without their own,
+// published with expvar as DefaultMetricsName
+func DefaultMetrics() *ExpvarMetrics {
+
+	defaultMetricsOnce.Do(func() {
+		var err error
+		if defaultMetrics, err = NewExpvarMetrics(DefaultMetricsName); err != nil {
+			// the name is taken by something else; keep the metrics unpublished
+			defaultMetrics, _ = NewExpvarMetrics("")
+		}
+	})
+
+	return defaultMetrics
+
+}
+
+// metrics returns the client's Metrics, or the default metrics if it has none
+func (c *Client) metrics() Metrics {
+
+	if c.config.Metrics != nil {
+		return c.config.Metrics
+	}
+
+	return DefaultMetrics()
+
+}
+
+// ObserveRequest will count the request, its latency, and the bytes sent
+func (m *ExpvarMetrics) ObserveRequest(method string, status int, duration time.Duration, bytesSent int64) {
+
+	m.requests.Add(fmt.Sprintf("%s %d", method, status), 1)
+	m.latency.observe(method, duration.Seconds())
+	m.bytesSent.Add(bytesSent)
+
+}
+
+// AddRecords will count the records sent
+func (m *ExpvarMetrics) AddRecords(recordType string, count int) {
+	m.records.Add(recordType, int64(count))
+}
+
+// Handler returns an http.Handler, which serves the metrics in the Prometheus text format
+func (m *ExpvarMetrics) Handler() http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = m.WritePrometheus(w)
+	})
+
+}
+
+// WritePrometheus will write the metrics in the Prometheus text exposition format
+func (m *ExpvarMetrics) WritePrometheus(w io.Writer) error {
+
+	buffer := bufio.NewWriter(w)
+
+	fmt.Fprintln(buffer, "# HELP hostdb_client_requests_total Requests made to HostDB, by method and status.")
+	fmt.Fprintln(buffer, "# TYPE hostdb_client_requests_total counter")
+	m.requests.Do(func(kv expvar.KeyValue) {
+		method, status := kv.Key, ""
+		if i := strings.LastIndex(kv.Key, " "); i >= 0 {
+			method, status = kv.Key[:i], kv.Key[i+1:]
+		}
+		fmt.Fprintf(buffer, "hostdb_client_requests_total{method=%s,status=%s} %s\n", quoteLabel(method), quoteLabel(status), kv.Value)
+	})
+
+	fmt.Fprintln(buffer, "# HELP hostdb_client_request_duration_seconds Latency of requests made to HostDB, by method.")
+	fmt.Fprintln(buffer, "# TYPE hostdb_client_request_duration_seconds histogram")
+	m.latency.writePrometheus(buffer, "hostdb_client_request_duration_seconds", "method")
+
+	fmt.Fprintln(buffer, "# HELP hostdb_client_sent_bytes_total Bytes sent to HostDB, in request bodies.")
+	fmt.Fprintln(buffer, "# TYPE hostdb_client_sent_bytes_total counter")
+	fmt.Fprintf(buffer, "hostdb_client_sent_bytes_total %d\n", m.bytesSent.Value())
+
+	fmt.Fprintln(buffer, "# HELP hostdb_client_sent_records_total Records sent to HostDB, by type.")
+	fmt.Fprintln(buffer, "# TYPE hostdb_client_sent_records_total counter")
+	m.records.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(buffer, "hostdb_client_sent_records_total{type=%s} %s\n", quoteLabel(kv.Key), kv.Value)
+	})
+
+	return buffer.Flush()
+
+}
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	counts []int64 // one per bucket, plus +Inf
+	sum    float64
+	count  int64
+}
+
+// histograms is a set of histograms, by label value, which is also an expvar.Var
+type histograms struct {
+	mutex sync.Mutex
+	byKey map[string]*histogram
+}
+
+func (h *histograms) observe(key string, value float64) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	hist, found := h.byKey[key]
+	if !found {
+		hist = &histogram{counts: make([]int64, len(latencyBuckets)+1)}
+		h.byKey[key] = hist
+	}
+
+	i := sort.SearchFloat64s(latencyBuckets, value)
+	hist.counts[i]++
+	hist.sum += value
+	hist.count++
+
+}
+
+// keys returns the label values, sorted
+func (h *histograms) keys() []string {
+
+	keys := make([]string, 0, len(h.byKey))
+	for k := range h.byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+
+}
+
+// String implements expvar.Var, encoding each histogram with cumulative bucket counts
+func (h *histograms) String() string {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	type bucket struct {
+		LE    string `json:"le"`
+		Count int64  `json:"count"`
+	}
+
+	type snapshot struct {
+		Buckets []bucket `json:"buckets"`
+		Sum     float64  `json:"sum"`
+		Count   int64    `json:"count"`
+	}
+
+	snapshots := map[string]snapshot{}
+	for _, k := range h.keys() {
+		hist := h.byKey[k]
+
+		var cumulative int64
+		s := snapshot{Sum: hist.sum, Count: hist.count}
+		for i, count := range hist.counts {
+			cumulative += count
+			s.Buckets = append(s.Buckets, bucket{LE: bucketLabel(i), Count: cumulative})
+		}
+		snapshots[k] = s
+	}
+
+	snapshotBytes, err := json.Marshal(snapshots)
+	if err != nil {
+		return "{}"
+	}
+
+	return string(snapshotBytes)
+
+}
+
+func (h *histograms) writePrometheus(w io.Writer, name string, label string) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, k := range h.keys() {
+		hist := h.byKey[k]
+
+		var cumulative int64
+		for i, count := range hist.counts {
+			cumulative += count
+			fmt.Fprintf(w, "%s_bucket{%s=%s,le=%s} %d\n", name, label, quoteLabel(k), quoteLabel(bucketLabel(i)), cumulative)
+		}
+
+		fmt.Fprintf(w, "%s_sum{%s=%s} %s\n", name, label, quoteLabel(k), strconv.FormatFloat(hist.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s=%s} %d\n", name, label, quoteLabel(k), hist.count)
+	}
+
+}
+
+// labelEscaper escapes a label value, as required by the Prometheus text format; unlike strconv.Quote,
+// every other character (including any non-ASCII) is written as it is
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel returns the label value, escaped and quoted for the Prometheus text format
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// bucketLabel returns the upper bound of a bucket, as written by Prometheus
+func bucketLabel(i int) string {
+
+	if i >= len(latencyBuckets) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64)
+
+}
@@ -0,0 +1,113 @@
+package hostdb
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExpvarMetrics(t *testing.T) {
+
+	m, err := NewExpvarMetrics("hostdb_test_metrics")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	m.AddRecords("test", 3)
+	assert.NotNil(t, expvar.Get("hostdb_test_metrics"), "published")
+	assert.Contains(t, expvar.Get("hostdb_test_metrics").String(), "\"sent_records\": {\"test\": 3}", "records by type")
+
+	// the name can't be reused
+	_, err = NewExpvarMetrics("hostdb_test_metrics")
+	assert.Error(t, err, "already published")
+
+}
+
+func TestExpvarMetrics_WritePrometheus(t *testing.T) {
+
+	m, err := NewExpvarMetrics("")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	m.ObserveRequest(http.MethodPost, http.StatusOK, 20*time.Millisecond, 100)
+	m.ObserveRequest(http.MethodPost, http.StatusServiceUnavailable, 2*time.Second, 100)
+	m.ObserveRequest(http.MethodGet, 0, time.Minute*2, 0)
+	m.AddRecords("test", 2)
+	m.AddRecords("café \"a\\b\"\n", 1)
+
+	var buffer bytes.Buffer
+	if err := m.WritePrometheus(&buffer); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	output := buffer.String()
+	for _, line := range []string{
+		"# TYPE hostdb_client_requests_total counter",
+		"hostdb_client_requests_total{method=\"GET\",status=\"0\"} 1",
+		"hostdb_client_requests_total{method=\"POST\",status=\"200\"} 1",
+		"hostdb_client_requests_total{method=\"POST\",status=\"503\"} 1",
+		"# TYPE hostdb_client_request_duration_seconds histogram",
+		"hostdb_client_request_duration_seconds_bucket{method=\"POST\",le=\"0.01\"} 0",
+		"hostdb_client_request_duration_seconds_bucket{method=\"POST\",le=\"0.025\"} 1",
+		"hostdb_client_request_duration_seconds_bucket{method=\"POST\",le=\"2.5\"} 2",
+		"hostdb_client_request_duration_seconds_bucket{method=\"GET\",le=\"60\"} 0",
+		"hostdb_client_request_duration_seconds_bucket{method=\"GET\",le=\"+Inf\"} 1",
+		"hostdb_client_request_duration_seconds_sum{method=\"POST\"} 2.02",
+		"hostdb_client_request_duration_seconds_count{method=\"POST\"} 2",
+		"hostdb_client_sent_bytes_total 200",
+		"hostdb_client_sent_records_total{type=\"test\"} 2",
+		`hostdb_client_sent_records_total{type="café \"a\\b\"\n"} 1`,
+	} {
+		assert.Contains(t, output, line+"\n", "exposition")
+	}
+
+	// served over http
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, output, recorder.Body.String(), "served exposition")
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"), "content type")
+
+}
+
+func TestClient_Metrics(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	m, err := NewExpvarMetrics("")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Metrics: m})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var expected bytes.Buffer
+	if err := TestRecordSet.EncodeJSON(&expected); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "1", m.requests.Get("POST 200").String(), "requests by method and status")
+	assert.Equal(t, int64(expected.Len()), m.bytesSent.Value(), "bytes sent")
+	assert.Equal(t, fmt.Sprint(len(TestRecordSet.Records)), m.records.Get(TestRecordSet.Type).String(), "records by type")
+
+}
@@ -0,0 +1,94 @@
+package hostdb
+
+import (
+	"encoding/json"
+)
+
+// RecordPatch is an RFC 7396 JSON merge patch for the Context and Data of a record.
+// Keys set to nil are removed; objects are merged recursively; anything else is replaced.
+type RecordPatch struct {
+	Context map[string]interface{} `json:"context,omitempty"`
+	Data    json.RawMessage        `json:"data,omitempty"`
+}
+
+// Apply will return a copy of the record, with the patch merged into its Context and Data
+func (p RecordPatch) Apply(r Record) (Record, error) {
+
+	if p.Context != nil {
+		targetBytes, err := json.Marshal(r.Context)
+		if err != nil {
+			return r, err
+		}
+
+		patchBytes, err := json.Marshal(p.Context)
+		if err != nil {
+			return r, err
+		}
+
+		contextBytes, err := MergePatch(targetBytes, patchBytes)
+		if err != nil {
+			return r, err
+		}
+
+		var context map[string]interface{}
+		if err := json.Unmarshal(contextBytes, &context); err != nil {
+			return r, err
+		}
+		r.Context = context
+	}
+
+	if len(p.Data) > 0 {
+		data, err := MergePatch(r.Data, p.Data)
+		if err != nil {
+			return r, err
+		}
+		r.Data = data
+	}
+
+	return r, nil
+
+}
+
+// MergePatch will apply an RFC 7396 JSON merge patch to the target document
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+
+	var targetValue interface{}
+	if len(target) > 0 {
+		if err := json.Unmarshal(target, &targetValue); err != nil {
+			return nil, err
+		}
+	}
+
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(targetValue, patchValue))
+
+}
+
+// mergePatch implements the MergePatch algorithm from RFC 7396, section 2
+func mergePatch(target interface{}, patch interface{}) interface{} {
+
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+
+}
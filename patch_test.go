@@ -0,0 +1,72 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+
+	// test cases from RFC 7396, appendix A
+	cases := []struct {
+		target string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{``, `{"a":1}`, `{"a":1}`},
+	}
+
+	for _, c := range cases {
+		result, err := MergePatch([]byte(c.target), []byte(c.patch))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		assert.JSONEq(t, c.result, string(result), "%s + %s", c.target, c.patch)
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.Error(t, err, "invalid patch")
+
+}
+
+func TestRecordPatch_Apply(t *testing.T) {
+
+	record := Record{
+		ID:      "abc",
+		Context: map[string]interface{}{"region": "us-west", "project": "test"},
+		Data:    json.RawMessage(`{"flavor":"m1.small","tags":{"env":"dev","owner":"me"}}`),
+	}
+
+	patch := RecordPatch{
+		Context: map[string]interface{}{"project": nil, "zone": "a"},
+		Data:    json.RawMessage(`{"tags":{"env":"prod","owner":null}}`),
+	}
+
+	patched, err := patch.Apply(record)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, map[string]interface{}{"region": "us-west", "zone": "a"}, patched.Context, "patched context")
+	assert.JSONEq(t, `{"flavor":"m1.small","tags":{"env":"prod"}}`, string(patched.Data), "patched data")
+	assert.Equal(t, "test", record.Context["project"], "the original record is unchanged")
+
+}
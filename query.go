@@ -0,0 +1,105 @@
+package hostdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// query parameters which control paging
+const (
+	QueryParamLimit  = "_limit"
+	QueryParamOffset = "_offset"
+)
+
+// QueryParamAppend is set when posting a record set, so its records are added to the stored records
+// with the same Type and Context, rather than replacing them; see BatchConfig
+const QueryParamAppend = "_append"
+
+// appendQueryString is added to the path of a set which is appended
+const appendQueryString = "&" + QueryParamAppend + "=true"
+
+// RecordQuery describes which records should be retrieved from HostDB
+type RecordQuery struct {
+	Type     string
+	Hostname string
+	IP       string
+
+	// any other query parameters, as configured in APIv0Config.QueryParams (e.g. flavor)
+	Params map[string]string
+
+	// paging; zero values are omitted, leaving the server defaults
+	Limit  int
+	Offset int
+}
+
+// Values will convert the RecordQuery into URL query parameters
+func (q RecordQuery) Values() url.Values {
+
+	values := url.Values{}
+
+	for k, v := range q.Params {
+		values.Set(k, v)
+	}
+
+	if q.Type != "" {
+		values.Set("type", q.Type)
+	}
+
+	if q.Hostname != "" {
+		values.Set("hostname", q.Hostname)
+	}
+
+	if q.IP != "" {
+		values.Set("ip", q.IP)
+	}
+
+	if q.Limit > 0 {
+		values.Set(QueryParamLimit, strconv.Itoa(q.Limit))
+	}
+
+	if q.Offset > 0 {
+		values.Set(QueryParamOffset, strconv.Itoa(q.Offset))
+	}
+
+	return values
+
+}
+
+// GetRecords will retrieve the records matching the query
+func (c *Client) GetRecords(query RecordQuery) (GetRecordsResponse, error) {
+	return c.GetRecordsContext(context.Background(), query)
+}
+
+// GetRecordsContext will retrieve the records matching the query, until ctx is canceled
+func (c *Client) GetRecordsContext(ctx context.Context, query RecordQuery) (response GetRecordsResponse, err error) {
+
+	path := "/records/"
+	if values := query.Values(); len(values) > 0 {
+		path = fmt.Sprintf("%s?%s", path, values.Encode())
+	}
+
+	err = c.get(ctx, path, &response)
+
+	return response, err
+
+}
+
+// GetRecord will retrieve a single record by ID
+func (c *Client) GetRecord(id string) (Record, error) {
+	return c.GetRecordContext(context.Background(), id)
+}
+
+// GetRecordContext will retrieve a single record by ID, until ctx is canceled
+func (c *Client) GetRecordContext(ctx context.Context, id string) (record Record, err error) {
+
+	if id == "" {
+		return record, ErrMissingID
+	}
+
+	err = c.get(ctx, fmt.Sprintf("/records/%s", url.PathEscape(id)), &record)
+
+	return record, err
+
+}
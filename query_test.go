@@ -0,0 +1,98 @@
+package hostdb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordQuery_Values(t *testing.T) {
+
+	assert.Empty(t, RecordQuery{}.Values(), "empty query")
+
+	query := RecordQuery{
+		Type:     "openstack",
+		Hostname: "m2.local",
+		IP:       "10.0.0.1",
+		Params:   map[string]string{"flavor": "m1.small"},
+		Limit:    10,
+		Offset:   20,
+	}
+
+	assert.Equal(t, "_limit=10&_offset=20&flavor=m1.small&hostname=m2.local&ip=10.0.0.1&type=openstack", query.Values().Encode(), "encoded query")
+
+}
+
+func TestClient_GetRecords(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "method")
+		assert.Equal(t, "/records/", r.URL.Path, "path")
+		assert.Equal(t, "openstack", r.URL.Query().Get("type"), "type")
+		assert.Equal(t, "m1.small", r.URL.Query().Get("flavor"), "query param")
+
+		_, err := fmt.Fprintln(w, "{\"count\":1,\"query_time\":\"1ms\",\"records\":{\"abc\":{\"id\":\"abc\",\"type\":\"openstack\",\"hostname\":\"m2.local\"}}}")
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	// reads don't require credentials
+	client, err := NewClient(ClientConfig{URL: testServer.URL})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	response, err := client.GetRecords(RecordQuery{Type: "openstack", Params: map[string]string{"flavor": "m1.small"}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 1, response.Count, "count")
+	assert.Equal(t, "m2.local", response.Records["abc"].Hostname, "decoded record")
+
+}
+
+func TestClient_GetRecord(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/records/abc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, err := fmt.Fprintln(w, "{\"id\":\"abc\",\"type\":\"openstack\",\"hostname\":\"m2.local\"}")
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	record, err := client.GetRecord("abc")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "abc", record.ID, "id")
+	assert.Equal(t, "m2.local", record.Hostname, "hostname")
+
+	_, err = client.GetRecord("missing")
+
+	var errorResponse ErrorResponse
+	assert.True(t, errors.As(err, &errorResponse), "error response")
+	assert.Equal(t, http.StatusNotFound, errorResponse.Code, "not found")
+
+	_, err = client.GetRecord("")
+	assert.Error(t, err, "empty ID")
+
+}
@@ -0,0 +1,118 @@
+package hostdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DeleteRecord will remove a single record from HostDB
+func (c *Client) DeleteRecord(id string) (DeleteRecordResponse, error) {
+	return c.DeleteRecordContext(context.Background(), id)
+}
+
+// DeleteRecordContext will remove a single record from HostDB, until ctx is canceled
+func (c *Client) DeleteRecordContext(ctx context.Context, id string) (response DeleteRecordResponse, err error) {
+
+	if id == "" {
+		return response, ErrMissingID
+	}
+
+	path := fmt.Sprintf("/records/%s", url.PathEscape(id))
+
+	responseBytes, err := c.request(ctx, http.MethodDelete, path, id, nil, nil)
+	if err != nil {
+		return response, err
+	}
+
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return response, err
+	}
+
+	if !response.OK {
+		return response, fmt.Errorf("failed to delete record %s", id)
+	}
+
+	return response, nil
+
+}
+
+// DeleteRecords will remove every record matching the query from HostDB.
+// A query without any filter is refused, rather than deleting everything, as is a query with a
+// Limit or Offset, since a delete can't be paged.
+func (c *Client) DeleteRecords(query RecordQuery) (DeleteRecordsResponse, error) {
+	return c.DeleteRecordsContext(context.Background(), query)
+}
+
+// DeleteRecordsContext will remove every record matching the query from HostDB, until ctx is canceled
+func (c *Client) DeleteRecordsContext(ctx context.Context, query RecordQuery) (response DeleteRecordsResponse, err error) {
+
+	if query.Limit != 0 || query.Offset != 0 {
+		return response, errors.New("refusing to delete records with a limit or offset")
+	}
+
+	// paging parameters aren't a filter, and would otherwise let an empty query through
+	values := query.Values()
+	values.Del(QueryParamLimit)
+	values.Del(QueryParamOffset)
+	if len(values) < 1 {
+		return response, errors.New("refusing to delete records without a query")
+	}
+
+	path := fmt.Sprintf("/records/?%s", values.Encode())
+
+	responseBytes, err := c.request(ctx, http.MethodDelete, path, path, nil, nil)
+	if err != nil {
+		return response, err
+	}
+
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return response, err
+	}
+
+	if !response.OK {
+		return response, errors.New(response.Error)
+	}
+
+	return response, nil
+
+}
+
+// PatchRecord will update the Context and/or Data of a single record, using an RFC 7396 JSON merge patch
+func (c *Client) PatchRecord(id string, patch RecordPatch) (PatchRecordResponse, error) {
+	return c.PatchRecordContext(context.Background(), id, patch)
+}
+
+// PatchRecordContext will update the Context and/or Data of a single record, until ctx is canceled
+func (c *Client) PatchRecordContext(ctx context.Context, id string, patch RecordPatch) (response PatchRecordResponse, err error) {
+
+	if id == "" {
+		return response, ErrMissingID
+	}
+
+	responseBytes, err := c.request(
+		ctx,
+		http.MethodPatch,
+		fmt.Sprintf("/records/%s", url.PathEscape(id)),
+		id,
+		patch,
+		map[string]string{"Content-Type": "application/merge-patch+json"},
+	)
+	if err != nil {
+		return response, err
+	}
+
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return response, err
+	}
+
+	if !response.OK {
+		return response, fmt.Errorf("failed to patch record %s", id)
+	}
+
+	return response, nil
+
+}
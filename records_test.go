@@ -0,0 +1,93 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_DeleteAndPatch(t *testing.T) {
+
+	var lastRequest *http.Request
+	var lastBody []byte
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+
+		var err error
+		lastBody, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		var body string
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/records/abc":
+			body = "{\"id\":\"abc\",\"ok\":true}"
+		case r.Method == http.MethodDelete && r.URL.Path == "/records/":
+			body = "{\"count\":3,\"ok\":true}"
+		case r.Method == http.MethodPatch:
+			body = "{\"id\":\"abc\",\"ok\":true}"
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		if _, err := fmt.Fprintln(w, body); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// delete a single record
+	deleted, err := client.DeleteRecord("abc")
+	if assert.NoError(t, err, "delete record") {
+		assert.Equal(t, DeleteRecordResponse{ID: "abc", OK: true}, deleted, "delete response")
+	}
+
+	_, err = client.DeleteRecord("")
+	assert.Error(t, err, "empty ID")
+
+	// delete many records
+	deletedRecords, err := client.DeleteRecords(RecordQuery{Type: "openstack", Hostname: "old.local"})
+	if assert.NoError(t, err, "delete records") {
+		assert.Equal(t, 3, deletedRecords.Count, "deleted count")
+		assert.Equal(t, "old.local", lastRequest.URL.Query().Get("hostname"), "query")
+	}
+
+	_, err = client.DeleteRecords(RecordQuery{})
+	assert.Error(t, err, "refuses to delete everything")
+
+	lastRequest = nil
+	_, err = client.DeleteRecords(RecordQuery{Limit: 1})
+	assert.Error(t, err, "paging is not a query")
+
+	_, err = client.DeleteRecords(RecordQuery{Type: "openstack", Offset: 10})
+	assert.Error(t, err, "deletes can't be paged")
+
+	_, err = client.DeleteRecords(RecordQuery{Params: map[string]string{QueryParamLimit: "1"}})
+	assert.Error(t, err, "paging parameter is not a query")
+	assert.Nil(t, lastRequest, "nothing was sent")
+
+	// patch a record
+	patched, err := client.PatchRecord("abc", RecordPatch{Context: map[string]interface{}{"zone": nil}})
+	if assert.NoError(t, err, "patch record") {
+		assert.Equal(t, PatchRecordResponse{ID: "abc", OK: true}, patched, "patch response")
+		assert.Equal(t, "application/merge-patch+json", lastRequest.Header.Get("Content-Type"), "content type")
+
+		var patch map[string]interface{}
+		if err := json.Unmarshal(lastBody, &patch); err != nil {
+			t.Fatal(err.Error())
+		}
+		assert.Equal(t, map[string]interface{}{"context": map[string]interface{}{"zone": nil}}, patch, "merge patch body")
+	}
+
+}
@@ -0,0 +1,120 @@
+package hostdb
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryPolicy is a reasonable policy for collectors which run unattended
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// RetryPolicy describes how requests which fail with a connection error,
+// a 429 or a 5xx status are retried. The zero value disables retries.
+type RetryPolicy struct {
+	// total number of attempts, including the first
+	MaxAttempts int `json:"max_attempts" mapstructure:"max_attempts"`
+
+	// the delay doubles after each attempt, starting at BaseDelay, up to MaxDelay;
+	// MaxDelay also caps any Retry-After delay requested by the server
+	BaseDelay time.Duration `json:"base_delay" mapstructure:"base_delay"`
+	MaxDelay  time.Duration `json:"max_delay" mapstructure:"max_delay"`
+
+	// fraction (0 to 1) of each delay which is randomized
+	Jitter float64 `json:"jitter" mapstructure:"jitter"`
+}
+
+// RetryAttempt describes a failed attempt, which is about to be retried
+type RetryAttempt struct {
+	UniqueIdentifier string
+	Method           string
+	Path             string
+	Attempt          int
+	Delay            time.Duration
+	Err              error
+}
+
+// attempts returns the total number of attempts allowed
+func (p RetryPolicy) attempts() int {
+
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+
+	return p.MaxAttempts
+
+}
+
+// delay returns how long to wait after the given attempt has failed;
+// a Retry-After duration provided by the server takes precedence, but is still capped at MaxDelay
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+
+	delay := p.BaseDelay
+	if delay <= 0 {
+		delay = DefaultRetryPolicy.BaseDelay
+	}
+
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryPolicy.MaxDelay
+	}
+
+	if retryAfter > 0 {
+		if retryAfter > maxDelay {
+			return maxDelay
+		}
+		return retryAfter
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(float64(delay) * jitter * rand.Float64())
+	}
+
+	return delay
+
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// parseRetryAfter will parse a Retry-After header, in either seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+
+}
@@ -0,0 +1,124 @@
+package hostdb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_delay(t *testing.T) {
+
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		MaxDelay:    5 * time.Second,
+	}
+
+	assert.Equal(t, 1, RetryPolicy{}.attempts(), "retries disabled by default")
+	assert.Equal(t, 5, policy.attempts(), "max attempts")
+
+	assert.Equal(t, time.Second, policy.delay(1, 0), "base delay")
+	assert.Equal(t, 2*time.Second, policy.delay(2, 0), "exponential delay")
+	assert.Equal(t, 4*time.Second, policy.delay(3, 0), "exponential delay")
+	assert.Equal(t, 5*time.Second, policy.delay(4, 0), "max delay")
+	assert.Equal(t, 3*time.Second, policy.delay(4, 3*time.Second), "Retry-After takes precedence")
+	assert.Equal(t, 5*time.Second, policy.delay(1, 24*time.Hour), "Retry-After is capped at the max delay")
+	assert.Equal(t, DefaultRetryPolicy.MaxDelay, RetryPolicy{}.delay(1, 24*time.Hour), "Retry-After is capped at the default max delay")
+
+	// jitter only ever shortens the delay
+	policy.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		delay := policy.delay(1, 0)
+		assert.True(t, delay > 500*time.Millisecond && delay <= time.Second, "jittered delay %v", delay)
+	}
+
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	now := time.Date(2003, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now), "no header")
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now), "seconds")
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now), "HTTP date")
+	assert.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-time.Hour).Format(http.TimeFormat), now), "date in the past")
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now), "invalid header")
+
+}
+
+func TestClient_Retry(t *testing.T) {
+
+	// fake http server which fails twice before succeeding
+	var requests int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, err := fmt.Fprintln(w, "{\"ok\":true}")
+			if err != nil {
+				t.Error(err.Error())
+			}
+		}
+	}))
+	defer testServer.Close()
+
+	var attempts []RetryAttempt
+	client, err := NewClient(ClientConfig{
+		URL:   testServer.URL,
+		Pass:  "pass",
+		Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		OnRetry: func(attempt RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "retry-test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 3, requests, "requests")
+	if assert.Len(t, attempts, 2, "retry hook calls") {
+		assert.Equal(t, "retry-test", attempts[0].UniqueIdentifier, "unique identifier")
+		assert.Equal(t, "POST", attempts[0].Method, "method")
+		assert.Equal(t, 1, attempts[0].Attempt, "attempt")
+		assert.Equal(t, 2, attempts[1].Attempt, "attempt")
+	}
+
+	// client errors are not retried
+	requests = 0
+	badRequestServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer badRequestServer.Close()
+
+	client, err = NewClient(ClientConfig{
+		URL:   badRequestServer.URL,
+		Pass:  "pass",
+		Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = client.SendRecord(TestRecord, "test")
+
+	var errorResponse ErrorResponse
+	assert.True(t, errors.As(err, &errorResponse), "error response")
+	assert.Equal(t, http.StatusBadRequest, errorResponse.Code, "status code")
+	assert.Equal(t, 1, requests, "bad requests are not retried")
+
+}
@@ -0,0 +1,180 @@
+package hostdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSendConcurrency is the number of record sets sent at once, unless otherwise specified
+const DefaultSendConcurrency = 4
+
+// SendOptions controls how SendRecordSets uploads many record sets
+type SendOptions struct {
+	// the number of record sets sent at once; defaults to DefaultSendConcurrency
+	Concurrency int
+
+	// the maximum number of requests per second, across all workers; zero means unlimited
+	RateLimit float64
+}
+
+// SendResult describes the outcome of sending a single RecordSet
+type SendResult struct {
+	Index    int // the position of the RecordSet in the input
+	Type     string
+	Records  int
+	Duration time.Duration
+	Err      error // nil, if the RecordSet was sent successfully
+}
+
+// SendError is returned when one or more record sets could not be sent
+type SendError struct {
+	Results []SendResult
+}
+
+func (e *SendError) Error() string {
+
+	failed := e.Failed()
+
+	messages := make([]string, 0, len(failed))
+	for _, result := range failed {
+		messages = append(messages, fmt.Sprintf("record set %d (%s): %v", result.Index, result.Type, result.Err))
+	}
+
+	return fmt.Sprintf("%d of %d record sets failed: %s", len(failed), len(e.Results), strings.Join(messages, "; "))
+
+}
+
+// Failed returns the results of the record sets which could not be sent
+func (e *SendError) Failed() (failed []SendResult) {
+
+	for _, result := range e.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+
+}
+
+// SendRecordSets will post many record sets to HostDB, with bounded concurrency,
+// over the client's shared connections. A result is returned for every RecordSet,
+// in the same order as the input, along with a *SendError if any of them failed.
+func (c *Client) SendRecordSets(ctx context.Context, recordSets []RecordSet, options SendOptions) ([]SendResult, error) {
+
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultSendConcurrency
+	}
+
+	if options.RateLimit > 0 {
+		ctx = withRateLimiter(ctx, newRateLimiter(options.RateLimit))
+	}
+
+	results := make([]SendResult, len(recordSets))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(recordSets); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				rs := recordSets[index]
+				start := time.Now()
+
+				err := ctx.Err()
+				if err == nil {
+					err = c.SendRecordSetContext(ctx, rs, "")
+				}
+
+				results[index] = SendResult{
+					Index:    index,
+					Type:     rs.Type,
+					Records:  len(rs.Records),
+					Duration: time.Since(start),
+					Err:      err,
+				}
+			}
+		}()
+	}
+
+	for i := range recordSets {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Err != nil {
+			return results, &SendError{Results: results}
+		}
+	}
+
+	return results, nil
+
+}
+
+// rateLimiter spaces requests evenly, at no more than a given rate
+type rateLimiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	next     time.Time
+}
+
+type rateLimiterKey struct{}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// withRateLimiter returns a context, which limits the rate of the requests made with it
+func withRateLimiter(ctx context.Context, limiter *rateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey{}, limiter)
+}
+
+// waitForRateLimiter will block until the context's rate limiter, if any, allows another request
+func waitForRateLimiter(ctx context.Context) error {
+
+	limiter, ok := ctx.Value(rateLimiterKey{}).(*rateLimiter)
+	if !ok {
+		return nil
+	}
+
+	return limiter.wait(ctx)
+
+}
+
+// wait will reserve the next available slot, and block until it arrives
+func (l *rateLimiter) wait(ctx context.Context) error {
+
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+	l.mutex.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+
+}
@@ -0,0 +1,121 @@
+package hostdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SendRecordSets(t *testing.T) {
+
+	// fake http server, which tracks how many requests are in flight, and rejects the "bad" type
+	var mutex sync.Mutex
+	var inFlight, maxInFlight, requests int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		defer func() {
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+
+		var rs RecordSet
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			t.Error(err.Error())
+		}
+
+		if rs.Type == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var recordSets []RecordSet
+	for i := 0; i < 10; i++ {
+		rs := newBatchRecordSet(i + 1)
+		if i == 4 {
+			rs.Type = "bad"
+		}
+		recordSets = append(recordSets, rs)
+	}
+
+	results, err := client.SendRecordSets(context.Background(), recordSets, SendOptions{Concurrency: 3})
+
+	var sendError *SendError
+	if !assert.True(t, errors.As(err, &sendError), "send error") {
+		t.FailNow()
+	}
+
+	assert.Len(t, results, 10, "a result for every record set")
+	assert.Equal(t, 10, requests, "every record set is sent")
+	assert.True(t, maxInFlight <= 3, "bounded concurrency, %d in flight", maxInFlight)
+
+	for i, result := range results {
+		assert.Equal(t, i, result.Index, "results are in order")
+		assert.Equal(t, i+1, result.Records, "records")
+	}
+
+	failed := sendError.Failed()
+	if assert.Len(t, failed, 1, "failed record sets") {
+		assert.Equal(t, 4, failed[0].Index, "failed index")
+		assert.Equal(t, "bad", failed[0].Type, "failed type")
+	}
+
+	// all successful
+	results, err = client.SendRecordSets(context.Background(), recordSets[:4], SendOptions{})
+	assert.NoError(t, err, "no failures")
+	assert.Len(t, results, 4, "results")
+
+}
+
+func TestClient_SendRecordSets_RateLimit(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	recordSets := []RecordSet{TestRecordSet, TestRecordSet, TestRecordSet, TestRecordSet, TestRecordSet}
+
+	start := time.Now()
+	if _, err := client.SendRecordSets(context.Background(), recordSets, SendOptions{Concurrency: 5, RateLimit: 50}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.True(t, time.Since(start) >= 80*time.Millisecond, "requests are spaced out, took %v", time.Since(start))
+
+}
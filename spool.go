@@ -0,0 +1,520 @@
+package hostdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpoolConfig describes where payloads are kept when HostDB is unreachable, until they can be resent
+type SpoolConfig struct {
+	Dir string `json:"dir" mapstructure:"dir"`
+
+	// entries older than this are discarded; zero means entries never expire
+	MaxAge time.Duration `json:"max_age" mapstructure:"max_age"`
+
+	// the oldest entries are discarded to keep the spool below this size; zero means no limit
+	MaxBytes int64 `json:"max_bytes" mapstructure:"max_bytes"`
+}
+
+// SpoolEntry is a request which could not be delivered, as it's stored in the spool directory
+type SpoolEntry struct {
+	Method           string    `json:"method"`
+	Path             string    `json:"path"`
+	UniqueIdentifier string    `json:"unique_identifier"`
+	Attempts         int       `json:"attempts"`
+	FirstFailure     time.Time `json:"first_failure"`
+	LastFailure      time.Time `json:"last_failure"`
+
+	// the payload must remain the last field, so it can be streamed
+	Payload json.RawMessage `json:"payload"`
+}
+
+// SpoolFlushResult describes what happened to the spooled entries during a flush
+type SpoolFlushResult struct {
+	Sent      int // delivered, and removed from the spool
+	Expired   int // older than MaxAge, and removed from the spool
+	Rejected  int // refused by HostDB or corrupt, and moved into the rejected subdirectory
+	Remaining int // still waiting to be delivered
+}
+
+// SpooledError is returned when a request could not be delivered, but its payload has been
+// written to the spool, to be resent by FlushSpool
+type SpooledError struct {
+	File string
+	Err  error
+}
+
+func (e *SpooledError) Error() string {
+	return fmt.Sprintf("%v (spooled into %s)", e.Err, e.File)
+}
+
+// Unwrap returns the error which caused the payload to be spooled
+func (e *SpooledError) Unwrap() error {
+	return e.Err
+}
+
+// the subdirectory of the spool, for entries which HostDB has refused, or which can't be decoded
+const spoolRejectedDir = "rejected"
+
+// errCorruptSpoolEntry is wrapped by the errors for spooled entries which can't be decoded
+var errCorruptSpoolEntry = errors.New("failed to decode spooled entry")
+
+// spool manages the entries in a spool directory.
+// Entries are named by the time of their first failure, so they sort in the order they were spooled,
+// followed by a key for their method and path, so the entries for a request can be found without reading them.
+type spool struct {
+	config   SpoolConfig
+	logger   func() Logger
+	mutex    sync.Mutex
+	sequence uint64
+}
+
+// FlushSpool will resend the entries spooled by the default client
+func FlushSpool(ctx context.Context) (SpoolFlushResult, error) {
+
+	client, err := getDefaultClient()
+	if err != nil {
+		return SpoolFlushResult{}, err
+	}
+
+	return client.FlushSpool(ctx)
+
+}
+
+// FlushSpool will resend spooled entries in the order they were spooled, stopping at the first
+// entry which still can't be delivered. Expired entries are discarded, and entries which HostDB
+// refuses (e.g. with a 400), or which can't be decoded, are moved into the rejected subdirectory
+// of the spool. Entries which were superseded by a later request are discarded when it succeeds,
+// so they're never resent over newer data.
+func (c *Client) FlushSpool(ctx context.Context) (result SpoolFlushResult, err error) {
+
+	if c.spool == nil {
+		return result, errors.New("no spool directory has been configured")
+	}
+
+	if c.config.DryRun {
+		return result, errors.New("spooled entries can't be flushed in dry-run mode")
+	}
+
+	if c.auth == nil {
+		return result, ErrMissingCredentials
+	}
+
+	files, err := c.spool.files()
+	if err != nil {
+		return result, err
+	}
+
+	for i, file := range files {
+
+		if err := ctx.Err(); err != nil {
+			result.Remaining = len(files) - i
+			return result, err
+		}
+
+		entry, err := c.spool.read(file)
+		if errors.Is(err, errCorruptSpoolEntry) {
+			// a corrupt entry would otherwise block every entry after it, forever
+			c.logger().Warn("failed to decode spooled entry", Fields{"file": file, "error": err})
+			if err := c.spool.reject(file); err != nil {
+				return result, err
+			}
+			result.Rejected++
+			continue
+		} else if err != nil {
+			// the entry may be readable later, e.g. once there are file descriptors to spare
+			result.Remaining = len(files) - i
+			return result, err
+		}
+
+		if c.spool.expired(entry.FirstFailure, time.Now()) {
+			if err := c.spool.remove(file); err != nil {
+				return result, err
+			}
+			result.Expired++
+			continue
+		}
+
+		_, attempts, retry, sendErr := c.send(ctx, entry.Method, entry.Path, entry.UniqueIdentifier, entry.Payload, nil)
+		entry.Attempts += attempts
+
+		switch {
+		case sendErr == nil:
+			if err := c.spool.remove(file); err != nil {
+				return result, err
+			}
+			result.Sent++
+		case retry:
+			// HostDB is still unreachable; keep this and every following entry, in order
+			entry.LastFailure = time.Now()
+			if err := c.spool.rewrite(file, entry); err != nil {
+				c.logger().Warn("failed to update spooled entry", Fields{"file": file, "error": err})
+			}
+			result.Remaining = len(files) - i
+			return result, sendErr
+		default:
+			c.logger().Warn("HostDB rejected spooled entry", Fields{
+				"file":              file,
+				"unique_identifier": entry.UniqueIdentifier,
+				"error":             sendErr,
+			})
+			if err := c.spool.reject(file); err != nil {
+				return result, err
+			}
+			result.Rejected++
+		}
+
+	}
+
+	return result, nil
+
+}
+
+// add will atomically write a new entry into the spool, streaming the payload
+func (s *spool) add(method string, path string, uniqueIdentifier string, attempts int, payload interface{}) (file string, err error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.config.Dir, 0700); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	entry := SpoolEntry{
+		Method:           method,
+		Path:             path,
+		UniqueIdentifier: uniqueIdentifier,
+		Attempts:         attempts,
+		FirstFailure:     now,
+		LastFailure:      now,
+	}
+
+	tmpFile, err := ioutil.TempFile(s.config.Dir, ".spool-*")
+	if err != nil {
+		return "", err
+	}
+
+	size, err := writeSpoolEntry(tmpFile, entry, payload)
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	if err := s.makeRoom(size); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	file = filepath.Join(s.config.Dir, fmt.Sprintf(
+		"%020d-%d-%06d-%s.json", now.UnixNano(), os.Getpid(), atomic.AddUint64(&s.sequence, 1), spoolKey(method, path),
+	))
+
+	if err := os.Rename(tmpFile.Name(), file); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	if err := syncDir(s.config.Dir); err != nil {
+		return "", err
+	}
+
+	return file, nil
+
+}
+
+// makeRoom will discard expired entries, and then the oldest entries, until size bytes will fit
+func (s *spool) makeRoom(size int64) error {
+
+	if s.config.MaxBytes > 0 && size > s.config.MaxBytes {
+		return fmt.Errorf("payload of %d bytes is larger than the spool", size)
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	sizes := make([]int64, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		sizes[i] = info.Size()
+		total += sizes[i]
+	}
+
+	now := time.Now()
+	for i, file := range files {
+		full := s.config.MaxBytes > 0 && total+size > s.config.MaxBytes
+		firstFailure := spoolFileTime(file)
+		if !full && (firstFailure.IsZero() || !s.expired(firstFailure, now)) {
+			break
+		}
+
+		s.log().Warn("discarding spooled entry", Fields{"file": file, "full": full})
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		total -= sizes[i]
+	}
+
+	return nil
+
+}
+
+// log returns the Logger of the spool's client
+func (s *spool) log() Logger {
+
+	if s.logger == nil {
+		return getLogger()
+	}
+
+	return s.logger()
+
+}
+
+// files returns the spooled entries, oldest first
+func (s *spool) files() ([]string, error) {
+
+	infos, err := ioutil.ReadDir(s.config.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		files = append(files, filepath.Join(s.config.Dir, info.Name()))
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+
+}
+
+// expired reports whether an entry first spooled at the given time is older than MaxAge
+func (s *spool) expired(firstFailure time.Time, now time.Time) bool {
+	return s.config.MaxAge > 0 && now.Sub(firstFailure) > s.config.MaxAge
+}
+
+// read will decode a spooled entry; an entry which can't be decoded is errCorruptSpoolEntry
+func (s *spool) read(file string) (entry SpoolEntry, err error) {
+
+	fileBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return entry, err
+	}
+
+	if err := json.Unmarshal(fileBytes, &entry); err != nil {
+		return entry, fmt.Errorf("%w %s: %v", errCorruptSpoolEntry, file, err)
+	}
+
+	return entry, nil
+
+}
+
+// rewrite will atomically replace an existing entry
+func (s *spool) rewrite(file string, entry SpoolEntry) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tmpFile, err := ioutil.TempFile(s.config.Dir, ".spool-*")
+	if err != nil {
+		return err
+	}
+
+	payload := entry.Payload
+	entry.Payload = nil
+
+	if _, err := writeSpoolEntry(tmpFile, entry, payload); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+
+	if err := os.Rename(tmpFile.Name(), file); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return syncDir(s.config.Dir)
+
+}
+
+// supersede will discard the entries for the same method and path as a request which succeeded,
+// which were spooled before it started, as resending them would overwrite newer data
+func (s *spool) supersede(method string, path string, start time.Time) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	key := spoolKey(method, path)
+	for _, file := range files {
+		if spoolFileKey(file) != key || !spoolFileTime(file).Before(start) {
+			continue
+		}
+
+		s.log().Info("discarding spooled entry, superseded by a later request", Fields{"file": file, "method": method, "path": path})
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func (s *spool) remove(file string) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return os.Remove(file)
+
+}
+
+// reject will move an entry out of the way, so it's kept for inspection, but never resent
+func (s *spool) reject(file string) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rejectedDir := filepath.Join(s.config.Dir, spoolRejectedDir)
+	if err := os.MkdirAll(rejectedDir, 0700); err != nil {
+		return err
+	}
+
+	if err := os.Rename(file, filepath.Join(rejectedDir, filepath.Base(file))); err != nil {
+		return err
+	}
+
+	return syncDir(s.config.Dir)
+
+}
+
+// writeSpoolEntry will write the entry into file, streaming the payload, and sync and close it,
+// so it's safely on disk before being renamed into place
+func writeSpoolEntry(file *os.File, entry SpoolEntry, payload interface{}) (size int64, err error) {
+
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	// replace the empty payload with the streamed one
+	suffix := []byte("null}")
+	if !bytes.HasSuffix(entryBytes, suffix) {
+		return 0, errors.New("unexpected spool entry encoding")
+	}
+
+	if _, err := file.Write(entryBytes[:len(entryBytes)-len(suffix)]); err != nil {
+		return 0, err
+	}
+
+	if err := encodeJSON(file, payload); err != nil {
+		return 0, err
+	}
+
+	if _, err := file.Write([]byte("}")); err != nil {
+		return 0, err
+	}
+
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+
+}
+
+// syncDir will flush a directory, so the entries renamed into it survive a crash
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	return d.Close()
+
+}
+
+// spoolKey identifies the entries for a method and path. A posted set which is appended to an earlier
+// batch has the same key as the set itself, so it's superseded when the whole set is sent again.
+func spoolKey(method string, path string) string {
+
+	path = strings.TrimSuffix(path, appendQueryString)
+	sum := sha256.Sum256([]byte(method + " " + path))
+
+	return hex.EncodeToString(sum[:8])
+
+}
+
+// spoolFileKey returns the key of the method and path, from the name of a spooled entry
+func spoolFileKey(file string) string {
+
+	parts := strings.Split(strings.TrimSuffix(filepath.Base(file), ".json"), "-")
+	if len(parts) != 4 {
+		return ""
+	}
+
+	return parts[3]
+
+}
+
+// spoolFileTime returns the time of the first failure, from the name of a spooled entry
+func spoolFileTime(file string) time.Time {
+
+	name := filepath.Base(file)
+	if i := strings.Index(name, "-"); i > 0 {
+		if nanoseconds, err := strconv.ParseInt(name[:i], 10, 64); err == nil {
+			return time.Unix(0, nanoseconds)
+		}
+	}
+
+	return time.Time{}
+
+}
@@ -0,0 +1,303 @@
+package hostdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Spool(t *testing.T) {
+
+	// fake http server, which is unavailable until told otherwise
+	status := http.StatusServiceUnavailable
+	var received []RecordSet
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		var rs RecordSet
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			t.Error(err.Error())
+		}
+		received = append(received, rs)
+
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	dir := filepath.Join(t.TempDir(), "spool")
+	client, err := NewClient(ClientConfig{
+		URL:   testServer.URL,
+		Pass:  "pass",
+		Retry: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+		Spool: SpoolConfig{Dir: dir},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	first := newBatchRecordSet(2)
+	second := newBatchRecordSet(3)
+
+	for _, rs := range []RecordSet{first, second} {
+		err := client.SendRecordSet(rs, "test")
+
+		var spooledError *SpooledError
+		assert.True(t, errors.As(err, &spooledError), "spooled error")
+
+		var errorResponse ErrorResponse
+		assert.True(t, errors.As(err, &errorResponse), "original error is preserved")
+		assert.Equal(t, http.StatusServiceUnavailable, errorResponse.Code, "status code")
+	}
+
+	files, err := client.spool.files()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !assert.Len(t, files, 2, "spooled entries") {
+		t.FailNow()
+	}
+
+	entry, err := client.spool.read(files[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, "POST", entry.Method, "method")
+	assert.Equal(t, "/records/?test", entry.Path, "path")
+	assert.Equal(t, 2, entry.Attempts, "attempts")
+	assert.False(t, entry.FirstFailure.IsZero(), "first failure")
+
+	// still unavailable
+	result, err := client.FlushSpool(context.Background())
+	assert.Error(t, err, "flush while unavailable")
+	assert.Equal(t, 2, result.Remaining, "remaining")
+
+	entry, err = client.spool.read(files[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, 4, entry.Attempts, "attempts are accumulated")
+
+	// available again
+	status = http.StatusOK
+	result, err = client.FlushSpool(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, SpoolFlushResult{Sent: 2}, result, "flush result")
+	if assert.Len(t, received, 2, "resent record sets") {
+		assert.Len(t, received[0].Records, 2, "resent in order")
+		assert.Len(t, received[1].Records, 3, "resent in order")
+	}
+
+	files, err = client.spool.files()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Empty(t, files, "empty spool")
+
+}
+
+func TestClient_FlushSpool_RejectedAndExpired(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer testServer.Close()
+
+	dir := t.TempDir()
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Spool: SpoolConfig{Dir: dir, MaxAge: time.Hour}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := client.spool.add("POST", "/records/", "test", 1, TestRecordSet); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// an entry which is too old to be resent
+	expired, err := client.spool.add("PUT", "/records/abc", "test", 1, TestRecord)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	entry, err := client.spool.read(expired)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	entry.FirstFailure = time.Now().Add(-2 * time.Hour)
+	if err := client.spool.rewrite(expired, entry); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result, err := client.FlushSpool(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, SpoolFlushResult{Expired: 1, Rejected: 1}, result, "flush result")
+
+	rejected, err := filepath.Glob(filepath.Join(dir, spoolRejectedDir, "*.json"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, rejected, 1, "rejected entries are kept")
+
+}
+
+func TestClient_FlushSpool_Corrupt(t *testing.T) {
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "{\"ok\":true}")
+	}))
+	defer testServer.Close()
+
+	dir := t.TempDir()
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Spool: SpoolConfig{Dir: dir}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	corrupt, err := client.spool.add("POST", "/records/", "test", 1, TestRecordSet)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := ioutil.WriteFile(corrupt, []byte(`{"method":"POST","payl`), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := client.spool.add("PUT", "/records/test", "test", 1, TestRecord); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the corrupt entry is set aside, rather than blocking the entry after it
+	result, err := client.FlushSpool(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, SpoolFlushResult{Sent: 1, Rejected: 1}, result, "flush result")
+	assert.FileExists(t, filepath.Join(dir, spoolRejectedDir, filepath.Base(corrupt)), "corrupt entry is kept")
+
+	// an entry which can't be read at the moment is left where it is
+	if _, err := client.spool.add("PUT", "/records/test", "test", 1, TestRecord); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	missing := filepath.Join(dir, fmt.Sprintf("%020d-1-000001-%s.json", time.Now().Add(-time.Minute).UnixNano(), spoolKey("PUT", "/records/test")))
+	if err := os.Symlink(filepath.Join(dir, "missing"), missing); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result, err = client.FlushSpool(context.Background())
+	assert.Error(t, err, "read error")
+	assert.Equal(t, SpoolFlushResult{Remaining: 2}, result, "flush stops at the unreadable entry")
+
+	files, err := client.spool.files()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, files, 2, "nothing is rejected")
+
+}
+
+func TestClient_Spool_Superseded(t *testing.T) {
+
+	// fake http server, which is unavailable until told otherwise
+	status := http.StatusServiceUnavailable
+	var posts int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		posts++
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Spool: SpoolConfig{Dir: t.TempDir()}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// an earlier run is spooled, for this set and another
+	for _, uniqueQueryString := range []string{"test", "other"} {
+		var spooledError *SpooledError
+		assert.True(t, errors.As(client.SendRecordSet(newBatchRecordSet(2), uniqueQueryString), &spooledError), "spooled")
+	}
+
+	// which is superseded once a later run sends the same set
+	status = http.StatusOK
+	if err := client.SendRecordSet(newBatchRecordSet(3), "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result, err := client.FlushSpool(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, SpoolFlushResult{Sent: 1}, result, "only the other set is resent")
+	assert.Equal(t, 2, posts, "the stale set is never resent")
+
+}
+
+func TestSpool_MaxBytes(t *testing.T) {
+
+	s := &spool{config: SpoolConfig{Dir: t.TempDir(), MaxBytes: 1000}}
+
+	for i := 0; i < 5; i++ {
+		if _, err := s.add("POST", "/records/", "test", 1, TestRecordSet); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	files, err := s.files()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var total int64
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		total += info.Size()
+	}
+
+	assert.True(t, len(files) < 5, "oldest entries are discarded")
+	assert.True(t, total <= 1000, "spool size %d", total)
+
+	// a payload larger than the whole spool
+	_, err = s.add("POST", "/records/", "test", 1, newBatchRecordSet(100))
+	assert.Error(t, err, "oversized payload")
+
+}
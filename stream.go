@@ -0,0 +1,160 @@
+package hostdb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"sync/atomic"
+)
+
+// jsonEncoder is implemented by types which can stream their own JSON encoding
+type jsonEncoder interface {
+	EncodeJSON(w io.Writer) error
+}
+
+// EncodeJSON will write the JSON encoding of the RecordSet to w, one record at a time,
+// so memory use is proportional to a single record, rather than the whole set.
+// The output is identical to json.Marshal.
+func (rs RecordSet) EncodeJSON(w io.Writer) (err error) {
+
+	// encode everything except the records, which are always the last field
+	envelope := rs
+	envelope.Records = nil
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	buffer := bufio.NewWriter(w)
+
+	suffix := []byte("null}")
+	if rs.Records == nil || !bytes.HasSuffix(envelopeBytes, []byte(`"records":null}`)) {
+		// nothing to stream
+		recordSetBytes, err := json.Marshal(rs)
+		if err != nil {
+			return err
+		}
+
+		if _, err := buffer.Write(recordSetBytes); err != nil {
+			return err
+		}
+
+		return buffer.Flush()
+	}
+
+	if _, err := buffer.Write(envelopeBytes[:len(envelopeBytes)-len(suffix)]); err != nil {
+		return err
+	}
+
+	if err := buffer.WriteByte('['); err != nil {
+		return err
+	}
+
+	for i, record := range rs.Records {
+		if i > 0 {
+			if err := buffer.WriteByte(','); err != nil {
+				return err
+			}
+		}
+
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		if _, err := buffer.Write(recordBytes); err != nil {
+			return err
+		}
+	}
+
+	if _, err := buffer.WriteString("]}"); err != nil {
+		return err
+	}
+
+	return buffer.Flush()
+
+}
+
+// encodeJSON will stream v as JSON, if it's able to; otherwise it is marshaled
+func encodeJSON(w io.Writer, v interface{}) error {
+
+	if encoder, ok := v.(jsonEncoder); ok {
+		return encoder.EncodeJSON(w)
+	}
+
+	return json.NewEncoder(w).Encode(v)
+
+}
+
+// streamingBody is a request body, encoded on demand by a goroutine writing into a pipe
+type streamingBody struct {
+	*io.PipeReader
+	done  chan struct{}
+	err   error
+	bytes int64
+}
+
+// newStreamingBody will start encoding v (compressed, if requested) into a new request body
+func newStreamingBody(v interface{}, compress bool) *streamingBody {
+
+	reader, writer := io.Pipe()
+
+	body := &streamingBody{
+		PipeReader: reader,
+		done:       make(chan struct{}),
+	}
+
+	go func() {
+		var err error
+		if compress {
+			gzipWriter := gzip.NewWriter(writer)
+			if err = encodeJSON(gzipWriter, v); err == nil {
+				err = gzipWriter.Close()
+			}
+		} else {
+			err = encodeJSON(writer, v)
+		}
+
+		// errors caused by the reader going away aren't encoding errors
+		if err != nil && err != io.ErrClosedPipe {
+			body.err = err
+		}
+
+		// publish the error before the reader can see it, so it's known once the request fails
+		close(body.done)
+
+		_ = writer.CloseWithError(err)
+	}()
+
+	return body
+
+}
+
+// Read counts the bytes read by the transport, as they're sent
+func (b *streamingBody) Read(p []byte) (n int, err error) {
+
+	n, err = b.PipeReader.Read(p)
+	atomic.AddInt64(&b.bytes, int64(n))
+
+	return n, err
+
+}
+
+// bytesRead returns the number of bytes sent so far
+func (b *streamingBody) bytesRead() int64 {
+	return atomic.LoadInt64(&b.bytes)
+}
+
+// encodingError returns the error encountered while encoding the body, if it has finished
+func (b *streamingBody) encodingError() error {
+
+	select {
+	case <-b.done:
+		return b.err
+	default:
+		return nil
+	}
+
+}
@@ -0,0 +1,110 @@
+package hostdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordSet_EncodeJSON(t *testing.T) {
+
+	recordSets := map[string]RecordSet{
+		"test record set": TestRecordSet,
+		"nil records":     {Type: "test"},
+		"empty records":   {Type: "test", Records: []Record{}},
+		"many records":    newBatchRecordSet(25),
+		"escaped values": {
+			Type:    "<test>",
+			Context: map[string]interface{}{"a&b": "c"},
+			Records: []Record{{Hostname: "<host>", Data: json.RawMessage(`{ "spaced" : [1, 2] }`)}},
+		},
+	}
+
+	for name, rs := range recordSets {
+
+		expected, err := json.Marshal(rs)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var buffer bytes.Buffer
+		if err := rs.EncodeJSON(&buffer); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		assert.Equal(t, string(expected), buffer.String(), name)
+
+	}
+
+	// invalid data is an error
+	invalid := RecordSet{Records: []Record{{Data: json.RawMessage("{")}}}
+	assert.Error(t, invalid.EncodeJSON(&bytes.Buffer{}), "invalid data")
+
+}
+
+func TestClient_SendRecordSet_Streaming(t *testing.T) {
+
+	var received RecordSet
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err := fmt.Fprintln(w, "{\"ok\":true}"); err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	var retries int
+	client, err := NewClient(ClientConfig{
+		URL:   testServer.URL,
+		Pass:  "pass",
+		Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		OnRetry: func(attempt RetryAttempt) {
+			retries++
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rs := newBatchRecordSet(100)
+	if err := client.SendRecordSet(rs, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Len(t, received.Records, 100, "streamed records")
+
+	// a record set which can't be encoded isn't retried
+	invalid := RecordSet{Type: "test", Records: []Record{{Data: json.RawMessage("{")}}}
+	assert.Error(t, client.SendRecordSet(invalid, "test"), "encoding error")
+	assert.Equal(t, 0, retries, "encoding errors are not retried")
+
+}
+
+func TestStreamingBody_encodingError(t *testing.T) {
+
+	invalid := RecordSet{Type: "test", Records: []Record{{Data: json.RawMessage("{")}}}
+
+	// the error must be known as soon as the reader sees it, not some time later
+	for i := 0; i < 100; i++ {
+		body := newStreamingBody(invalid, i%2 == 0)
+
+		_, err := ioutil.ReadAll(body)
+		assert.Error(t, err, "read")
+
+		if !assert.Error(t, body.encodingError(), "encoding error, once the body fails") {
+			return
+		}
+	}
+
+}
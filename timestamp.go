@@ -0,0 +1,198 @@
+package hostdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimestampFormat is the format of record timestamps expected by the server, always in UTC
+const TimestampFormat = "2006-01-02 15:04:05"
+
+// timestampFormats are accepted when parsing timestamps; those without a zone are assumed to be UTC
+var timestampFormats = []string{
+	TimestampFormat,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -0700 MST",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// Timestamp is a time, which is always sent to HostDB in TimestampFormat, in UTC, to the second.
+// The zero value is sent as null, and is left out of a Record or RecordSet entirely.
+type Timestamp struct {
+	time.Time
+}
+
+// optionalTimestamp returns nil for the zero value, so it can be left out with omitempty
+func optionalTimestamp(t Timestamp) *Timestamp {
+
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+
+}
+
+// NewTimestamp will return the time as a Timestamp, in UTC, truncated to the second
+func NewTimestamp(t time.Time) Timestamp {
+
+	if t.IsZero() {
+		return Timestamp{}
+	}
+
+	return Timestamp{Time: t.UTC().Truncate(time.Second)}
+
+}
+
+// Now returns the current time as a Timestamp
+func Now() Timestamp {
+	return NewTimestamp(time.Now())
+}
+
+// ParseTimestamp will parse TimestampFormat, RFC 3339 and a few other common formats, or epoch seconds.
+// Times without a zone are assumed to be UTC.
+func ParseTimestamp(s string) (Timestamp, error) {
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Timestamp{}, nil
+	}
+
+	for _, format := range timestampFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return NewTimestamp(t), nil
+		}
+	}
+
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(seconds, 0) && !math.IsNaN(seconds) {
+		whole, fraction := math.Modf(seconds)
+		return NewTimestamp(time.Unix(int64(whole), int64(fraction*1e9))), nil
+	}
+
+	return Timestamp{}, fmt.Errorf("unrecognized timestamp %q; expected a format such as %s", s, TimestampFormat)
+
+}
+
+// MustParseTimestamp is like ParseTimestamp, but panics if the timestamp can't be parsed
+func MustParseTimestamp(s string) Timestamp {
+
+	t, err := ParseTimestamp(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+
+}
+
+// String returns the timestamp in TimestampFormat, or an empty string for the zero value
+func (t Timestamp) String() string {
+
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(TimestampFormat)
+
+}
+
+// MarshalJSON encodes the timestamp in TimestampFormat
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(t.String())
+
+}
+
+// MarshalText encodes the timestamp in TimestampFormat, e.g. when it's used as a map key,
+// rather than in the RFC 3339 format of the embedded time.Time
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// AppendText appends the timestamp in TimestampFormat, shadowing the method of the embedded time.Time,
+// which newer versions of encoding/json prefer to MarshalText
+func (t Timestamp) AppendText(b []byte) ([]byte, error) {
+	return append(b, t.String()...), nil
+}
+
+// UnmarshalText decodes any format understood by ParseTimestamp
+func (t *Timestamp) UnmarshalText(text []byte) error {
+
+	parsed, err := ParseTimestamp(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+
+	return nil
+
+}
+
+// UnmarshalJSON decodes any format understood by ParseTimestamp, from a string or a number
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		*t = Timestamp{}
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+
+	return nil
+
+}
+
+// Stamp returns a copy of the record set, with the current time given to the set and its records,
+// wherever the timestamp is unset
+func (rs RecordSet) Stamp() RecordSet {
+	return rs.stamp(Now())
+}
+
+func (rs RecordSet) stamp(now Timestamp) RecordSet {
+
+	if rs.Timestamp.IsZero() {
+		rs.Timestamp = now
+	}
+
+	records := make([]Record, len(rs.Records))
+	for i, r := range rs.Records {
+		if r.Timestamp.IsZero() {
+			r.Timestamp = now
+		}
+		records[i] = r
+	}
+
+	rs.Records = records
+
+	return rs
+
+}
@@ -0,0 +1,163 @@
+package hostdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestamp(t *testing.T) {
+
+	expected := time.Date(2003, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	for _, s := range []string{
+		"2003-04-05 06:07:08",
+		"2003-04-05T06:07:08Z",
+		"2003-04-05T06:07:08.123456Z",
+		"2003-04-04T23:07:08-07:00",
+		"2003-04-05T06:07:08",
+		"2003-04-04 23:07:08 -0700",
+		"2003-04-04 23:07:08 -0700 PDT",
+		"Sat, 05 Apr 2003 06:07:08 GMT",
+		"1049522828",
+		"1049522828.5",
+	} {
+		timestamp, err := ParseTimestamp(s)
+		if assert.NoError(t, err, s) {
+			assert.True(t, expected.Equal(timestamp.Time), "%s: %v", s, timestamp.Time)
+			assert.Equal(t, time.UTC, timestamp.Location(), "%s: UTC", s)
+		}
+	}
+
+	timestamp, err := ParseTimestamp("")
+	assert.NoError(t, err, "empty")
+	assert.True(t, timestamp.IsZero(), "zero")
+
+	_, err = ParseTimestamp("yesterday")
+	assert.Error(t, err, "unrecognized")
+
+	assert.Panics(t, func() { MustParseTimestamp("yesterday") }, "must parse")
+
+}
+
+func TestTimestamp_JSON(t *testing.T) {
+
+	type document struct {
+		Timestamp Timestamp `json:"timestamp"`
+	}
+
+	local := time.FixedZone("PDT", -7*60*60)
+	timestamp := NewTimestamp(time.Date(2003, 4, 4, 23, 7, 8, 999, local))
+
+	documentBytes, err := json.Marshal(document{Timestamp: timestamp})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"timestamp":"2003-04-05 06:07:08"}`, string(documentBytes), "canonical UTC format")
+
+	documentBytes, err = json.Marshal(document{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"timestamp":null}`, string(documentBytes), "zero value")
+
+	for input, expected := range map[string]Timestamp{
+		`{"timestamp":"2003-04-05T06:07:08Z"}`: timestamp,
+		`{"timestamp":1049522828}`:             timestamp,
+		`{"timestamp":"1049522828"}`:           timestamp,
+		`{"timestamp":null}`:                   {},
+		`{"timestamp":""}`:                     {},
+	} {
+		var d document
+		if assert.NoError(t, json.Unmarshal([]byte(input), &d), input) {
+			assert.Equal(t, expected, d.Timestamp, input)
+		}
+	}
+
+	var d document
+	assert.Error(t, json.Unmarshal([]byte(`{"timestamp":"yesterday"}`), &d), "unrecognized")
+	assert.Error(t, json.Unmarshal([]byte(`{"timestamp":true}`), &d), "not a timestamp")
+
+}
+
+func TestTimestamp_Text(t *testing.T) {
+
+	timestamp := MustParseTimestamp("2003-04-05 06:07:08")
+
+	keyBytes, err := json.Marshal(map[Timestamp]int{timestamp: 1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"2003-04-05 06:07:08":1}`, string(keyBytes), "map key in TimestampFormat")
+
+	var decoded map[Timestamp]int
+	if assert.NoError(t, json.Unmarshal([]byte(`{"2003-04-05T06:07:08Z":1}`), &decoded), "map key") {
+		assert.Equal(t, map[Timestamp]int{timestamp: 1}, decoded, "map key in any format")
+	}
+
+	var invalid Timestamp
+	assert.Error(t, invalid.UnmarshalText([]byte("yesterday")), "unrecognized")
+
+}
+
+func TestRecord_JSON_Timestamp(t *testing.T) {
+
+	recordBytes, err := json.Marshal(Record{ID: "a", Hostname: "alpha"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"id":"a","hostname":"alpha"}`, string(recordBytes), "unset timestamp is left out")
+
+	recordBytes, err = json.Marshal(Record{ID: "a", Timestamp: MustParseTimestamp("2003-04-05 06:07:08"), Committer: "test"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"id":"a","committer":"test","timestamp":"2003-04-05 06:07:08"}`, string(recordBytes), "set timestamp")
+
+	var record Record
+	if assert.NoError(t, json.Unmarshal(recordBytes, &record), "decode") {
+		assert.Equal(t, "2003-04-05 06:07:08", record.Timestamp.String(), "round trip")
+	}
+
+	// the same goes for a record set, whose records are still the last field
+	rs := RecordSet{Type: "test", Records: []Record{{ID: "a"}}}
+	recordSetBytes, err := json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, `{"type":"test","context":null,"records":[{"id":"a"}]}`, string(recordSetBytes), "unset set timestamp is left out")
+
+	var buffer bytes.Buffer
+	if err := rs.EncodeJSON(&buffer); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, string(recordSetBytes), buffer.String(), "streamed")
+
+}
+
+func TestRecordSet_Stamp(t *testing.T) {
+
+	now := MustParseTimestamp("2003-04-05 06:07:08")
+	earlier := MustParseTimestamp("2001-01-01 00:00:00")
+
+	rs := RecordSet{Records: []Record{{ID: "a"}, {ID: "b", Timestamp: earlier}}}
+	stamped := rs.stamp(now)
+
+	assert.Equal(t, now, stamped.Timestamp, "set")
+	assert.Equal(t, now, stamped.Records[0].Timestamp, "unset record")
+	assert.Equal(t, earlier, stamped.Records[1].Timestamp, "set record")
+	assert.True(t, rs.Records[0].Timestamp.IsZero(), "the caller's records are untouched")
+
+	assert.False(t, rs.Stamp().Timestamp.IsZero(), "current time")
+
+}
@@ -0,0 +1,75 @@
+package hostdb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// maxIdleConnsPerHost is the number of connections to HostDB kept open between requests
+const maxIdleConnsPerHost = 16
+
+// newTransport returns a transport dedicated to a single client, so TLS settings
+// never leak into http.DefaultTransport or any other client
+func newTransport(config ClientConfig) (*http.Transport, error) {
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	// keep enough idle connections for concurrent senders to reuse
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+
+	return transport, nil
+
+}
+
+// newTLSConfig builds the TLS configuration for a client;
+// the CA bundle is added to the system pool, and a client certificate is loaded for mTLS
+func newTLSConfig(config ClientConfig) (*tls.Config, error) {
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.Insecure,
+	}
+
+	if config.CAFile != "" {
+		pemBytes, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, errors.New("both a client certificate and key are required for mTLS")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+
+}
@@ -0,0 +1,85 @@
+package hostdb
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+
+	// verification is enabled by default
+	tlsConfig, err := newTLSConfig(ClientConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.False(t, tlsConfig.InsecureSkipVerify, "verification enabled by default")
+
+	// missing CA file
+	_, err = newTLSConfig(ClientConfig{CAFile: "/does/not/exist.pem"})
+	assert.Error(t, err, "missing CA file")
+
+	// a CA file without any certificates
+	emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+	if err := ioutil.WriteFile(emptyFile, []byte("nothing here"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = newTLSConfig(ClientConfig{CAFile: emptyFile})
+	assert.Error(t, err, "CA file without certificates")
+
+	// a certificate without a key
+	_, err = newTLSConfig(ClientConfig{CertFile: "client.pem"})
+	assert.Error(t, err, "client certificate without a key")
+
+}
+
+func TestClient_TLS(t *testing.T) {
+
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, "{\"ok\":true}")
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}))
+	defer testServer.Close()
+
+	// write the test server's certificate into a CA bundle
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, caBytes, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// trusting the custom CA bundle
+	client, err := NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", CAFile: caFile})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// skipping verification, scoped to this client only
+	client, err = NewClient(ClientConfig{URL: testServer.URL, Pass: "pass", Insecure: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := client.SendRecordSet(TestRecordSet, "test"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if defaultTLSConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig; defaultTLSConfig != nil {
+		assert.False(t, defaultTLSConfig.InsecureSkipVerify, "default transport still verifies certificates")
+	}
+
+}
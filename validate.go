@@ -0,0 +1,139 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// FieldError describes an invalid field of a record
+type FieldError struct {
+	Index   int // the position of the record in its record set, or -1 for a lone record
+	Field   string
+	Value   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+
+	if e.Index < 0 {
+		return fmt.Sprintf("%s %q: %s", e.Field, e.Value, e.Message)
+	}
+
+	return fmt.Sprintf("record %d: %s %q: %s", e.Index, e.Field, e.Value, e.Message)
+
+}
+
+// ValidationError lists every invalid field found by Validate
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fieldError.Error())
+	}
+
+	return fmt.Sprintf("%d invalid fields: %s", len(e.Errors), strings.Join(messages, "; "))
+
+}
+
+// Validate will check the record before it's sent; the error is a *ValidationError.
+// Type, Hostname and Timestamp are required; IP, Addresses and Data are optional, but must parse when provided.
+func (r Record) Validate() error {
+	return newValidationError(r.validate(-1, "", Timestamp{}))
+}
+
+// Validate will check the record set and all of its records before it's sent; the error is a *ValidationError.
+// The Type and Timestamp of the set are used for records which don't have their own.
+func (rs RecordSet) Validate() error {
+
+	var errs []FieldError
+
+	if rs.Type == "" {
+		errs = append(errs, FieldError{Index: -1, Field: "type", Message: "is required"})
+	}
+
+	for i, r := range rs.Records {
+		errs = append(errs, r.validate(i, rs.Type, rs.Timestamp)...)
+	}
+
+	return newValidationError(errs)
+
+}
+
+// validate returns the invalid fields of the record, falling back to the type and timestamp of its set
+func (r Record) validate(index int, setType string, setTimestamp Timestamp) (errs []FieldError) {
+
+	invalid := func(field string, value string, message string) {
+		errs = append(errs, FieldError{Index: index, Field: field, Value: value, Message: message})
+	}
+
+	if r.Type == "" && setType == "" {
+		invalid("type", r.Type, "is required")
+	}
+
+	if r.Hostname == "" {
+		invalid("hostname", r.Hostname, "is required")
+	} else if !validHostname(r.Hostname) {
+		invalid("hostname", r.Hostname, "is not a valid RFC 1123 hostname")
+	}
+
+	if r.IP != "" && net.ParseIP(r.IP) == nil {
+		invalid("ip", r.IP, "is not a valid IPv4 or IPv6 address")
+	}
+
+	if r.Timestamp.IsZero() && setTimestamp.IsZero() {
+		invalid("timestamp", "", "is required")
+	}
+
+	errs = append(errs, r.validateAddresses(index)...)
+
+	if len(r.Data) > 0 && !json.Valid(r.Data) {
+		invalid("data", string(r.Data), "is not valid JSON")
+	}
+
+	return errs
+
+}
+
+func newValidationError(errs []FieldError) error {
+
+	if len(errs) < 1 {
+		return nil
+	}
+
+	return &ValidationError{Errors: errs}
+
+}
+
+// validHostname reports whether the name is a valid RFC 1123 hostname; a trailing dot is allowed
+func validHostname(name string) bool {
+
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) < 1 || len(label) > 63 {
+			return false
+		}
+
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+
+}
@@ -0,0 +1,102 @@
+package hostdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord_Validate(t *testing.T) {
+
+	valid := Record{
+		Type:      "test",
+		Hostname:  "host-1.example.com",
+		IP:        "10.0.0.1",
+		Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
+		Data:      json.RawMessage(`{"ok":true}`),
+	}
+
+	assert.NoError(t, valid.Validate(), "valid record")
+
+	ipv6 := valid
+	ipv6.IP = "2001:db8::1"
+	assert.NoError(t, ipv6.Validate(), "IPv6 address")
+
+	invalid := Record{
+		Hostname: "-bad_host",
+		IP:       "10.0.0.256",
+		Data:     json.RawMessage(`{"ok":`),
+	}
+
+	err := invalid.Validate()
+
+	var validationError *ValidationError
+	if !assert.True(t, errors.As(err, &validationError), "validation error") {
+		return
+	}
+
+	var fields []string
+	for _, fieldError := range validationError.Errors {
+		fields = append(fields, fieldError.Field)
+		assert.Equal(t, -1, fieldError.Index, "lone record")
+	}
+
+	assert.Equal(t, []string{"type", "hostname", "ip", "timestamp", "data"}, fields, "invalid fields")
+	assert.Contains(t, err.Error(), "ip \"10.0.0.256\": is not a valid IPv4 or IPv6 address", "error message")
+
+}
+
+func TestRecordSet_Validate(t *testing.T) {
+
+	rs := RecordSet{
+		Type:      "test",
+		Timestamp: MustParseTimestamp("2003-04-05 06:07:08"),
+		Records: []Record{
+			{Hostname: "alpha", IP: "10.0.0.1"},
+			{Hostname: "", IP: "10.0.0.2"},
+			{Hostname: "charlie", IP: "::g"},
+		},
+	}
+
+	err := rs.Validate()
+
+	var validationError *ValidationError
+	if !assert.True(t, errors.As(err, &validationError), "validation error") {
+		return
+	}
+
+	assert.Equal(t, []FieldError{
+		{Index: 1, Field: "hostname", Message: "is required"},
+		{Index: 2, Field: "ip", Value: "::g", Message: "is not a valid IPv4 or IPv6 address"},
+	}, validationError.Errors, "the type and timestamp of the set are used")
+
+	// without a type or timestamp on the set, every record needs its own
+	rs = RecordSet{Records: []Record{{Hostname: "alpha"}}}
+
+	if assert.True(t, errors.As(rs.Validate(), &validationError), "validation error") {
+		assert.Len(t, validationError.Errors, 3, "set type, record type and record timestamp")
+	}
+
+}
+
+func TestValidHostname(t *testing.T) {
+
+	for name, valid := range map[string]bool{
+		"localhost":             true,
+		"host-1.example.com":    true,
+		"host-1.example.com.":   true,
+		"1host":                 true,
+		"":                      false,
+		"-host":                 false,
+		"host-":                 false,
+		"host..example.com":     false,
+		"host_1.example.com":    false,
+		strings.Repeat("a", 64): false,
+	} {
+		assert.Equal(t, valid, validHostname(name), name)
+	}
+
+}